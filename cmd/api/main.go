@@ -1,45 +1,78 @@
 package main
 
 import (
-	"errors"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/apikeys"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/certs"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/config"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/http"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/logging"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/metrics"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/tracing"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
-	"log"
+	"log/slog"
 	"os"
-	"strings"
+	"os/signal"
+	"syscall"
 )
 
-var validAPIKeys []string
-
 func main() {
 	godotenv.Load(".env")
-	err := getApiKeys()
+	cfg, err := config.Load()
 	if err != nil {
-		panic(err)
+		// Se listan todos los problemas y se sale antes de aceptar tráfico.
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
-	eng := gin.Default()
-	router := http.InitRouter(eng, nil)
-	router.MapRoutes(validAPIKeys)
-	if err := eng.Run(); err != nil {
+	logging.Setup(cfg.LogLevel)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		panic(err)
+	}
+	defer shutdownTracing(context.Background())
+	apiKeys, err := getApiKeys(cfg.APIKeys)
+	if err != nil {
 		panic(err)
 	}
+	apikeys.ReloadOnSIGHUP(apiKeys)
+	if cfg.Metrics.Addr != "" {
+		metricsServer := metrics.Serve(cfg.Metrics.Addr)
+		// Se cierra al final para que se pueda seguir el drenaje.
+		defer metricsServer.Close()
+	}
 
-}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-func getApiKeys() error {
-	mustGetenv := func(k string) string {
-		v := os.Getenv(k)
-		if v == "" {
-			log.Fatalf("Fatal Error in main.go: %s environment varaible not set.", k)
+	eng := gin.New()
+	router := http.InitRouter(eng, nil, cfg)
+	router.MapRoutes(ctx, apiKeys)
+	var tlsConfig *tls.Config
+	if cfg.TLS.Enabled() {
+		reloader, err := certs.NewReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.ClientCAFile)
+		if err != nil {
+			slog.Error("loading tls certificates", "error", err)
+			os.Exit(1)
 		}
-		return v
+		reloader.Watch(ctx, cfg.TLS.ReloadInterval)
+		tlsConfig = http.TLSConfig(cfg.TLS, reloader)
+	}
+	srv := http.NewServer(eng, cfg.Server, tlsConfig)
+	if err := http.Serve(ctx, srv, router, cfg.Server); err != nil {
+		slog.Error("server failed", "error", err)
+		os.Exit(1)
 	}
-	apiKeys := mustGetenv("VALID_API_KEYS")
-	validAPIKeys = strings.Split(apiKeys, ",")
-	if len(validAPIKeys) == 0 {
-		return errors.New("No Valid API keys found")
+}
+
+// getApiKeys loads the registry from API_KEYS_FILE and falls back to the
+// plain-text VALID_API_KEYS list used by older deployments.
+func getApiKeys(cfg config.APIKeys) (*apikeys.Registry, error) {
+	if cfg.File != "" {
+		return apikeys.LoadFile(cfg.File)
 	}
-	return nil
+	return apikeys.FromSecrets(cfg.Secrets)
 }
@@ -0,0 +1,14 @@
+package apikeys
+
+import "context"
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, key *Key) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+func FromContext(ctx context.Context) (*Key, bool) {
+	key, ok := ctx.Value(contextKey{}).(*Key)
+	return key, ok && key != nil
+}
@@ -0,0 +1,244 @@
+package apikeys
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/cors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ScopeCatalogRead  Scope = "catalog:read"
+	ScopeCatalogWrite Scope = "catalog:write"
+	ScopeAI           Scope = "ai"
+	ScopeAuth         Scope = "auth"
+	ScopeGraphQL      Scope = "graphql"
+)
+
+// Los hashes del archivo se escriben como "sha256:<hex>".
+const hashPrefix = "sha256:"
+
+var (
+	ErrUnknownKey = errors.New("unknown api key")
+	ErrExpiredKey = errors.New("expired api key")
+)
+
+type (
+	Scope string
+
+	Key struct {
+		Name      string     `json:"name" yaml:"name"`
+		Hash      string     `json:"hash" yaml:"hash"`
+		Scopes    []Scope    `json:"scopes" yaml:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+		Origins   []string   `json:"origins,omitempty" yaml:"origins,omitempty"`
+
+		digest []byte
+	}
+
+	file struct {
+		Keys []Key `json:"keys" yaml:"keys"`
+	}
+
+	// Registry holds the API keys accepted by the gateway. Keys loaded from a
+	// file can be swapped at runtime with Reload.
+	Registry struct {
+		path string
+		now  func() time.Time
+
+		mu   sync.RWMutex
+		keys []*Key
+	}
+)
+
+// AllScopes is what keys from VALID_API_KEYS get, since that format has no scopes.
+var AllScopes = []Scope{ScopeCatalogRead, ScopeCatalogWrite, ScopeAI, ScopeAuth, ScopeGraphQL}
+
+// LoadFile reads the registry from a .json, .yaml or .yml file.
+func LoadFile(path string) (*Registry, error) {
+	r := &Registry{path: path, now: time.Now}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// FromSecrets builds a registry from plain-text secrets, as listed in
+// VALID_API_KEYS. The secrets are hashed and get every scope.
+func FromSecrets(secrets []string) (*Registry, error) {
+	var keys []*Key
+	for i, secret := range secrets {
+		secret = strings.TrimSpace(secret)
+		if secret == "" {
+			continue
+		}
+		key := &Key{Name: fmt.Sprintf("env-%d", i+1), Hash: HashSecret(secret), Scopes: AllScopes}
+		if err := key.prepare(); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no valid api keys found")
+	}
+	return &Registry{now: time.Now, keys: keys}, nil
+}
+
+// HashSecret returns the value to store in the "hash" field for secret.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hashPrefix + hex.EncodeToString(sum[:])
+}
+
+// Reload reads the file again. On error the current keys stay in place.
+func (r *Registry) Reload() error {
+	if r.path == "" {
+		return nil
+	}
+	raw, err := os.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+
+	var f file
+	switch strings.ToLower(filepath.Ext(r.path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &f)
+	default:
+		err = json.Unmarshal(raw, &f)
+	}
+	if err != nil {
+		return fmt.Errorf("api keys file %s: %w", r.path, err)
+	}
+
+	keys := make([]*Key, 0, len(f.Keys))
+	names := make(map[string]bool)
+	for i := range f.Keys {
+		key := &f.Keys[i]
+		if err := key.prepare(); err != nil {
+			return fmt.Errorf("api keys file %s: %w", r.path, err)
+		}
+		if names[key.Name] {
+			return fmt.Errorf("api keys file %s: duplicated key name %q", r.path, key.Name)
+		}
+		names[key.Name] = true
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("api keys file %s: no keys", r.path)
+	}
+
+	r.mu.Lock()
+	r.keys = keys
+	r.mu.Unlock()
+	return nil
+}
+
+// Lookup finds the key matching secret. Every key is compared in constant
+// time, so the response time does not depend on which key matched.
+func (r *Registry) Lookup(secret string) (*Key, error) {
+	sum := sha256.Sum256([]byte(secret))
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var found *Key
+	for _, key := range r.keys {
+		if subtle.ConstantTimeCompare(sum[:], key.digest) == 1 {
+			found = key
+		}
+	}
+	if found == nil {
+		return nil, ErrUnknownKey
+	}
+	if found.ExpiresAt != nil && r.now().After(*found.ExpiresAt) {
+		return nil, ErrExpiredKey
+	}
+	return found, nil
+}
+
+// KnownOrigin reports whether some key lists origin, so the CORS policy
+// lets browsers on it through.
+func (r *Registry) KnownOrigin(origin string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, key := range r.keys {
+		for _, o := range key.Origins {
+			if o != cors.Wildcard && strings.EqualFold(o, origin) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (k *Key) Allows(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsOrigin reports whether a browser on origin may use the key. Keys
+// without origins are not restricted.
+func (k *Key) AllowsOrigin(origin string) bool {
+	if len(k.Origins) == 0 {
+		return true
+	}
+	for _, o := range k.Origins {
+		if o == cors.Wildcard || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *Key) prepare() error {
+	if k.Name == "" {
+		return errors.New("api key without name")
+	}
+	if !strings.HasPrefix(k.Hash, hashPrefix) {
+		return fmt.Errorf("api key %q: hash must start with %q", k.Name, hashPrefix)
+	}
+	digest, err := hex.DecodeString(strings.TrimPrefix(k.Hash, hashPrefix))
+	if err != nil || len(digest) != sha256.Size {
+		return fmt.Errorf("api key %q: invalid sha256 hash", k.Name)
+	}
+	for _, scope := range k.Scopes {
+		if !knownScope(scope) {
+			return fmt.Errorf("api key %q: unknown scope %q", k.Name, scope)
+		}
+	}
+	for i, origin := range k.Origins {
+		if origin == cors.Wildcard {
+			continue
+		}
+		u, err := cors.ParseOrigin(origin)
+		if err != nil || strings.HasPrefix(u.Hostname(), "*.") {
+			return fmt.Errorf("api key %q: invalid origin %q", k.Name, origin)
+		}
+		k.Origins[i] = u.String()
+	}
+	k.digest = digest
+	return nil
+}
+
+func knownScope(scope Scope) bool {
+	for _, s := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package apikeys
+
+import (
+	"testing"
+)
+
+func TestKeyOrigins(t *testing.T) {
+	registry := &Registry{keys: []*Key{
+		{Name: "web", Hash: HashSecret("web"), Origins: []string{"https://Web.example/", "http://localhost:3000"}},
+		{Name: "any", Hash: HashSecret("any"), Origins: []string{"*"}},
+		{Name: "open", Hash: HashSecret("open")},
+	}}
+	for _, key := range registry.keys {
+		if err := key.prepare(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	web, anyOrigin, open := registry.keys[0], registry.keys[1], registry.keys[2]
+
+	tests := []struct {
+		name   string
+		key    *Key
+		origin string
+		want   bool
+	}{
+		{"listed origin", web, "https://web.example", true},
+		{"listed origin in another case", web, "https://WEB.example", true},
+		{"origin with port", web, "http://localhost:3000", true},
+		{"other scheme", web, "http://web.example", false},
+		{"other origin", web, "https://evil.example", false},
+		{"wildcard key", anyOrigin, "https://evil.example", true},
+		{"key without origins", open, "https://evil.example", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key.AllowsOrigin(tt.origin); got != tt.want {
+				t.Fatalf("AllowsOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+
+	known := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://web.example", true},
+		{"http://localhost:3000", true},
+		// A "*" on a key does not open CORS to every origin.
+		{"https://evil.example", false},
+	}
+	for _, tt := range known {
+		if got := registry.KnownOrigin(tt.origin); got != tt.want {
+			t.Fatalf("KnownOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestKeyInvalidOrigin(t *testing.T) {
+	for _, origin := range []string{"web.example", "https://web.example/path", "https://*.web.example"} {
+		key := &Key{Name: "web", Hash: HashSecret("web"), Origins: []string{origin}}
+		if err := key.prepare(); err == nil {
+			t.Fatalf("prepare() accepted origin %q", origin)
+		}
+	}
+}
+
+func TestAllows(t *testing.T) {
+	key := &Key{Scopes: []Scope{ScopeCatalogRead, ScopeGraphQL}}
+	tests := []struct {
+		scope Scope
+		want  bool
+	}{
+		{ScopeCatalogRead, true},
+		{ScopeGraphQL, true},
+		{ScopeCatalogWrite, false},
+		{ScopeAI, false},
+	}
+	for _, tt := range tests {
+		if got := key.Allows(tt.scope); got != tt.want {
+			t.Fatalf("Allows(%q) = %v, want %v", tt.scope, got, tt.want)
+		}
+	}
+}
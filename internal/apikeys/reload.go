@@ -0,0 +1,27 @@
+package apikeys
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ReloadOnSIGHUP reloads r every time the process receives SIGHUP. A file
+// that fails to load is logged and the previous keys keep working.
+func ReloadOnSIGHUP(r *Registry) {
+	if r.path == "" {
+		return
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			if err := r.Reload(); err != nil {
+				slog.Error("api keys reload failed", "path", r.path, "error", err)
+				continue
+			}
+			slog.Info("api keys reloaded", "path", r.path)
+		}
+	}()
+}
@@ -0,0 +1,255 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/metrics"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/upstream"
+)
+
+type (
+	// Store keeps encoded entries. NewLRU is the in-process store; an
+	// external one (Redis, memcached) only needs to implement these methods
+	// and drop entries after StaleUntil.
+	Store interface {
+		Get(ctx context.Context, key string) (Entry, bool, error)
+		Set(ctx context.Context, key string, entry Entry) error
+		Delete(ctx context.Context, keys ...string) error
+	}
+
+	// Entry keeps the upstream validators of the load that produced Value,
+	// so a cached read answers with the same ETag and Last-Modified.
+	Entry struct {
+		Value        []byte    `json:"value"`
+		ETag         string    `json:"etag,omitempty"`
+		LastModified string    `json:"last_modified,omitempty"`
+		FreshUntil   time.Time `json:"fresh_until"`
+		StaleUntil   time.Time `json:"stale_until"`
+	}
+
+	// TTL is how long an entry is served as is, and for how long after that
+	// it is still served while a refresh runs in the background.
+	TTL struct {
+		Fresh time.Duration
+		Stale time.Duration
+	}
+
+	// Cache is a read-through cache over a Store. Concurrent misses of the
+	// same key share one load, and a nil *Cache just calls the loader.
+	Cache struct {
+		name    string
+		entries Store
+		now     func() time.Time
+		// loadTimeout bounds a shared load, which does not end with the
+		// request that started it.
+		loadTimeout time.Duration
+
+		mu       sync.Mutex
+		inflight map[string]*call
+		// versions cuenta las invalidaciones de cada clave mientras la
+		// cargan, para no guardar lo que se cargó antes de una escritura.
+		versions map[string]*version
+	}
+
+	call struct {
+		done  chan struct{}
+		entry Entry
+		err   error
+	}
+
+	version struct {
+		loads   int
+		current uint64
+	}
+)
+
+type bypassKey struct{}
+
+// Bypass makes Fetch skip cached entries for reads that must see the
+// upstream state, such as checking If-Match before a write. The fresh
+// value is still stored, and loads of the same key already running are not.
+func Bypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+func New(name string, store Store, loadTimeout time.Duration) *Cache {
+	return &Cache{
+		name:        name,
+		entries:     store,
+		now:         time.Now,
+		loadTimeout: loadTimeout,
+		inflight:    make(map[string]*call),
+		versions:    make(map[string]*version),
+	}
+}
+
+// Fetch returns the cached value of key or stores what load returns. Errors
+// are never cached, and a store that fails only costs a call upstream.
+func Fetch[T any](ctx context.Context, c *Cache, key string, ttl TTL, load func(context.Context) (T, error)) (T, error) {
+	var value T
+	if c == nil {
+		return load(ctx)
+	}
+
+	encodedLoad := func(ctx context.Context) ([]byte, error) {
+		v, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(v)
+	}
+
+	entry, err := c.get(ctx, key, ttl, encodedLoad)
+	if err != nil {
+		return value, err
+	}
+	if err := json.Unmarshal(entry.Value, &value); err != nil {
+		// Una entrada corrupta se descarta y se vuelve a pedir.
+		c.entries.Delete(ctx, key)
+		return load(ctx)
+	}
+	upstream.ValidatorsFrom(ctx).Add(entry.ETag, entry.LastModified)
+	return value, nil
+}
+
+// Invalidate drops keys so the next read goes upstream.
+func (c *Cache) Invalidate(ctx context.Context, keys ...string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	for _, key := range keys {
+		c.invalidate(key)
+	}
+	c.mu.Unlock()
+	if err := c.entries.Delete(ctx, keys...); err != nil {
+		slog.WarnContext(ctx, "cache invalidation failed", "cache", c.name, "keys", keys, "error", err)
+	}
+}
+
+func (c *Cache) get(ctx context.Context, key string, ttl TTL, load func(context.Context) ([]byte, error)) (Entry, error) {
+	if bypass, _ := ctx.Value(bypassKey{}).(bool); bypass {
+		// No se une a una carga en curso, que pudo empezar antes del cambio.
+		c.mu.Lock()
+		c.invalidate(key)
+		c.mu.Unlock()
+		return c.refresh(ctx, key, ttl, load)
+	}
+	entry, ok, err := c.entries.Get(ctx, key)
+	if err != nil {
+		slog.WarnContext(ctx, "cache read failed", "cache", c.name, "key", key, "error", err)
+	}
+	now := c.now()
+	switch {
+	case ok && now.Before(entry.FreshUntil):
+		metrics.CacheRequests.WithLabelValues(c.name, "hit").Inc()
+		return entry, nil
+	case ok && now.Before(entry.StaleUntil):
+		metrics.CacheRequests.WithLabelValues(c.name, "stale").Inc()
+		// El refresco sigue aunque el request termine, con sus mismos valores de contexto.
+		go c.load(context.WithoutCancel(ctx), key, ttl, load)
+		return entry, nil
+	}
+	metrics.CacheRequests.WithLabelValues(c.name, "miss").Inc()
+	return c.load(ctx, key, ttl, load)
+}
+
+// load runs load once per key at a time and stores the result. The load is
+// detached from ctx, so a caller that goes away does not fail the others
+// waiting for the same key; each caller still stops waiting when its own ctx
+// ends.
+func (c *Cache) load(ctx context.Context, key string, ttl TTL, load func(context.Context) ([]byte, error)) (Entry, error) {
+	c.mu.Lock()
+	current, ok := c.inflight[key]
+	if !ok {
+		current = &call{done: make(chan struct{})}
+		c.inflight[key] = current
+		go c.lead(context.WithoutCancel(ctx), key, ttl, load, current)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-current.done:
+		return current.entry, current.err
+	case <-ctx.Done():
+		return Entry{}, ctx.Err()
+	}
+}
+
+func (c *Cache) lead(ctx context.Context, key string, ttl TTL, load func(context.Context) ([]byte, error), current *call) {
+	if c.loadTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.loadTimeout)
+		defer cancel()
+	}
+	current.entry, current.err = c.refresh(ctx, key, ttl, load)
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	c.mu.Unlock()
+	close(current.done)
+}
+
+// refresh runs load and keeps the result unless an invalidation happened
+// meanwhile. The upstream validators are collected apart from the ones of
+// the request that started the load, since the entry serves other requests.
+func (c *Cache) refresh(ctx context.Context, key string, ttl TTL, load func(context.Context) ([]byte, error)) (Entry, error) {
+	started := c.startLoad(key)
+	loadCtx, validators := upstream.WithValidators(ctx)
+	value, err := load(loadCtx)
+	current := c.endLoad(key, started)
+	if err != nil {
+		return Entry{}, err
+	}
+	now := c.now()
+	entry := Entry{
+		Value:      value,
+		FreshUntil: now.Add(ttl.Fresh),
+		StaleUntil: now.Add(ttl.Fresh + ttl.Stale),
+	}
+	entry.ETag, entry.LastModified = validators.Get()
+	if !current {
+		return entry, nil
+	}
+	if err := c.entries.Set(ctx, key, entry); err != nil {
+		slog.WarnContext(ctx, "cache write failed", "cache", c.name, "key", key, "error", err)
+	}
+	return entry, nil
+}
+
+// startLoad returns the version of key a load starting now will see.
+func (c *Cache) startLoad(key string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.versions[key]
+	if !ok {
+		v = &version{}
+		c.versions[key] = v
+	}
+	v.loads++
+	return v.current
+}
+
+// endLoad reports whether key was not invalidated since startLoad returned
+// started. Keys are forgotten once no load is left.
+func (c *Cache) endLoad(key string, started uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v := c.versions[key]
+	v.loads--
+	if v.loads == 0 {
+		delete(c.versions, key)
+	}
+	return v.current == started
+}
+
+// invalidate discards the loads of key running now. c.mu must be held.
+func (c *Cache) invalidate(key string) {
+	if v, ok := c.versions[key]; ok {
+		v.current++
+	}
+}
@@ -0,0 +1,355 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/upstream"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func newTestCache() (*Cache, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewLRU(10)
+	store.now = clock.Now
+	c := New("test", store, time.Second)
+	c.now = clock.Now
+	return c, clock
+}
+
+// counter is a loader returning how many times it ran, or an error while
+// fail is set.
+type counter struct {
+	calls atomic.Int32
+	fail  atomic.Bool
+}
+
+func (l *counter) load(context.Context) (int32, error) {
+	n := l.calls.Add(1)
+	if l.fail.Load() {
+		return 0, errors.New("upstream down")
+	}
+	return n, nil
+}
+
+// settle waits until the loader ran calls times and no load is running, so
+// a refresh started in the background has been stored.
+func settle(t *testing.T, c *Cache, l *counter, calls int32) {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		started := l.calls.Load() >= calls
+		c.mu.Lock()
+		running := len(c.inflight)
+		c.mu.Unlock()
+		if started && running == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("loader ran %d times, want %d", l.calls.Load(), calls)
+}
+
+func TestFetchStaleWhileRevalidate(t *testing.T) {
+	c, clock := newTestCache()
+	ttl := TTL{Fresh: time.Minute, Stale: time.Minute}
+	loader := &counter{}
+	fetch := func(want, calls int32) {
+		t.Helper()
+		got, err := Fetch(context.Background(), c, "k", ttl, loader.load)
+		if err != nil {
+			t.Fatal(err)
+		}
+		settle(t, c, loader, calls)
+		if got != want {
+			t.Fatalf("Fetch() = %d, want %d", got, want)
+		}
+		if n := loader.calls.Load(); n != calls {
+			t.Fatalf("loader ran %d times, want %d", n, calls)
+		}
+	}
+
+	fetch(1, 1)
+	clock.Advance(30 * time.Second)
+	fetch(1, 1)
+
+	// Stale: the old value is served and a refresh runs behind it.
+	clock.Advance(45 * time.Second)
+	fetch(1, 2)
+	fetch(2, 2)
+
+	// Past the stale window the read waits for the loader.
+	clock.Advance(3 * time.Minute)
+	fetch(3, 3)
+
+	// A failed refresh keeps serving the stale entry.
+	clock.Advance(90 * time.Second)
+	loader.fail.Store(true)
+	fetch(3, 4)
+	fetch(3, 5)
+}
+
+func TestFetchErrorsAreNotCached(t *testing.T) {
+	c, _ := newTestCache()
+	loader := &counter{}
+	loader.fail.Store(true)
+	if _, err := Fetch(context.Background(), c, "k", TTL{Fresh: time.Minute}, loader.load); err == nil {
+		t.Fatal("Fetch() returned no error from a failing loader")
+	}
+	loader.fail.Store(false)
+	if got, err := Fetch(context.Background(), c, "k", TTL{Fresh: time.Minute}, loader.load); err != nil || got != 2 {
+		t.Fatalf("Fetch() = %d, %v, want a new load", got, err)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	c, _ := newTestCache()
+	loader := &counter{}
+	ttl := TTL{Fresh: time.Minute}
+	Fetch(context.Background(), c, "a", ttl, loader.load)
+	Fetch(context.Background(), c, "b", ttl, loader.load)
+
+	c.Invalidate(context.Background(), "a")
+	if got, _ := Fetch(context.Background(), c, "a", ttl, loader.load); got != 3 {
+		t.Fatalf("invalidated key = %d, want a new load", got)
+	}
+	if got, _ := Fetch(context.Background(), c, "b", ttl, loader.load); got != 2 {
+		t.Fatalf("other key = %d, want the cached 2", got)
+	}
+}
+
+func TestInvalidateDuringLoad(t *testing.T) {
+	c, _ := newTestCache()
+	ttl := TTL{Fresh: time.Minute}
+	loaded := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Fetch(context.Background(), c, "k", ttl, func(context.Context) (string, error) {
+			close(loaded)
+			<-release
+			return "before the write", nil
+		})
+	}()
+	<-loaded
+	c.Invalidate(context.Background(), "k")
+	close(release)
+	<-done
+
+	got, _ := Fetch(context.Background(), c, "k", ttl, func(context.Context) (string, error) {
+		return "after the write", nil
+	})
+	if got != "after the write" {
+		t.Fatalf("Fetch() = %q, a load started before Invalidate was stored", got)
+	}
+}
+
+func TestBypass(t *testing.T) {
+	c, _ := newTestCache()
+	ttl := TTL{Fresh: time.Minute}
+	ctx := context.Background()
+	value := func(v string) func(context.Context) (string, error) {
+		return func(context.Context) (string, error) { return v, nil }
+	}
+	Fetch(ctx, c, "k", ttl, value("cached"))
+
+	if got, _ := Fetch(Bypass(ctx), c, "k", ttl, value("upstream")); got != "upstream" {
+		t.Fatalf("Fetch(Bypass) = %q, want the upstream value", got)
+	}
+	if got, _ := Fetch(ctx, c, "k", ttl, value("reloaded")); got != "upstream" {
+		t.Fatalf("Fetch() after Bypass = %q, want the value it stored", got)
+	}
+}
+
+func TestBypassDuringLoads(t *testing.T) {
+	tests := []struct {
+		name    string
+		loading string
+		want    string
+	}{
+		{"same key", "k", "bypass"},
+		{"other key", "other", "slow load"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestCache()
+			ttl := TTL{Fresh: time.Minute}
+			ctx := context.Background()
+			loaded, release, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+			go func() {
+				defer close(done)
+				Fetch(ctx, c, tt.loading, ttl, func(context.Context) (string, error) {
+					close(loaded)
+					<-release
+					return "slow load", nil
+				})
+			}()
+			<-loaded
+			Fetch(Bypass(ctx), c, "k", ttl, func(context.Context) (string, error) { return "bypass", nil })
+			close(release)
+			<-done
+
+			got, _ := Fetch(ctx, c, tt.loading, ttl, func(context.Context) (string, error) { return "reloaded", nil })
+			if got != tt.want {
+				t.Fatalf("Fetch(%q) = %q, want %q", tt.loading, got, tt.want)
+			}
+			if len(c.versions) != 0 {
+				t.Fatalf("%d key versions kept after every load ended", len(c.versions))
+			}
+		})
+	}
+}
+
+func TestFetchConcurrentMisses(t *testing.T) {
+	c, _ := newTestCache()
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func(context.Context) (string, error) {
+		calls.Add(1)
+		<-release
+		return "value", nil
+	}
+
+	const callers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := Fetch(context.Background(), c, "k", TTL{Fresh: time.Minute}, load); err != nil || v != "value" {
+				t.Errorf("Fetch() = %q, %v", v, err)
+			}
+		}()
+	}
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// Give the other callers time to join the running load.
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("loader ran %d times, want 1", n)
+	}
+}
+
+func TestCancelledCallerDoesNotFailTheLoad(t *testing.T) {
+	c, _ := newTestCache()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	load := func(ctx context.Context) (string, error) {
+		close(started)
+		select {
+		case <-release:
+			return "value", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	firstCtx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := Fetch(firstCtx, c, "k", TTL{Fresh: time.Minute}, load)
+		first <- err
+	}()
+	<-started
+
+	second := make(chan string, 1)
+	go func() {
+		v, err := Fetch(context.Background(), c, "k", TTL{Fresh: time.Minute}, load)
+		if err != nil {
+			t.Error(err)
+		}
+		second <- v
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled caller: error = %v, want context.Canceled", err)
+	}
+	close(release)
+	if v := <-second; v != "value" {
+		t.Fatalf("other caller: Fetch() = %q, want %q", v, "value")
+	}
+}
+
+func TestLoadTimeout(t *testing.T) {
+	c := New("test", NewLRU(10), 10*time.Millisecond)
+	_, err := Fetch(context.Background(), c, "k", TTL{Fresh: time.Minute}, func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Fetch() error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache
+	c.Invalidate(context.Background(), "k")
+	v, err := Fetch(context.Background(), c, "k", TTL{}, func(context.Context) (string, error) { return "value", nil })
+	if err != nil || v != "value" {
+		t.Fatalf("Fetch() = %q, %v", v, err)
+	}
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	l := NewLRU(2)
+	entry := Entry{StaleUntil: time.Now().Add(time.Hour)}
+	l.Set(ctx, "a", entry)
+	l.Set(ctx, "b", entry)
+	l.Get(ctx, "a")
+	l.Set(ctx, "c", entry)
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok, _ := l.Get(ctx, key); ok != want {
+			t.Errorf("Get(%q) found = %v, want %v", key, ok, want)
+		}
+	}
+
+	l.Set(ctx, "d", Entry{StaleUntil: time.Now().Add(-time.Second)})
+	if _, ok, _ := l.Get(ctx, "d"); ok {
+		t.Error("Get() returned an entry past StaleUntil")
+	}
+}
+
+func TestFetchKeepsUpstreamValidators(t *testing.T) {
+	c, _ := newTestCache()
+	load := func(ctx context.Context) (string, error) {
+		upstream.ValidatorsFrom(ctx).Add(`"v1"`, "Tue, 01 Oct 2024 10:00:00 GMT")
+		return "value", nil
+	}
+	for _, read := range []string{"miss", "hit"} {
+		ctx, validators := upstream.WithValidators(context.Background())
+		if _, err := Fetch(ctx, c, "k", TTL{Fresh: time.Minute}, load); err != nil {
+			t.Fatal(err)
+		}
+		if etag, lastModified := validators.Get(); etag != `"v1"` || lastModified == "" {
+			t.Errorf("%s: validators = %q, %q, want the upstream ones", read, etag, lastModified)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+	// LRU is an in-process Store holding at most maxEntries entries and
+	// evicting the least recently used one when full.
+	LRU struct {
+		maxEntries int
+		now        func() time.Time
+
+		mu      sync.Mutex
+		order   *list.List
+		entries map[string]*list.Element
+	}
+
+	lruItem struct {
+		key   string
+		entry Entry
+	}
+)
+
+func NewLRU(maxEntries int) *LRU {
+	return &LRU{maxEntries: maxEntries, now: time.Now, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (l *LRU) Get(_ context.Context, key string) (Entry, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	elem, ok := l.entries[key]
+	if !ok {
+		return Entry{}, false, nil
+	}
+	item := elem.Value.(*lruItem)
+	if !l.now().Before(item.entry.StaleUntil) {
+		l.remove(elem)
+		return Entry{}, false, nil
+	}
+	l.order.MoveToFront(elem)
+	return item.entry, true, nil
+}
+
+func (l *LRU) Set(_ context.Context, key string, entry Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if elem, ok := l.entries[key]; ok {
+		elem.Value.(*lruItem).entry = entry
+		l.order.MoveToFront(elem)
+		return nil
+	}
+	l.entries[key] = l.order.PushFront(&lruItem{key: key, entry: entry})
+	for l.maxEntries > 0 && l.order.Len() > l.maxEntries {
+		l.remove(l.order.Back())
+	}
+	return nil
+}
+
+func (l *LRU) Delete(_ context.Context, keys ...string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		if elem, ok := l.entries[key]; ok {
+			l.remove(elem)
+		}
+	}
+	return nil
+}
+
+func (l *LRU) remove(elem *list.Element) {
+	l.order.Remove(elem)
+	delete(l.entries, elem.Value.(*lruItem).key)
+}
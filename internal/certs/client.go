@@ -0,0 +1,44 @@
+package certs
+
+import (
+	"crypto/tls"
+	"log/slog"
+)
+
+// ClientTLS builds the settings to call a server that uses a private CA
+// or requires a client certificate. Every file is optional; the client
+// certificate is read again on the next handshake after it changes.
+func ClientTLS(caFile, certFile, keyFile, serverName string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: serverName}
+	if caFile != "" {
+		pool, err := LoadPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		reloader, err := NewReloader(certFile, keyFile, "")
+		if err != nil {
+			return nil, err
+		}
+		cfg.GetClientCertificate = reloader.ClientCertificate
+	}
+	return cfg, nil
+}
+
+// ClientCertificate is a tls.Config.GetClientCertificate that reloads the
+// files when they changed since the last handshake. Pooled connections keep
+// handshakes rare, so checking the files here is cheap.
+func (r *Reloader) ClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if r.changed() {
+		if err := r.Reload(); err != nil {
+			slog.Error("tls client certificate reload failed", "cert_file", r.certFile, "error", err)
+		} else {
+			slog.Info("tls client certificate reloaded", "cert_file", r.certFile)
+		}
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
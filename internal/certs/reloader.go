@@ -0,0 +1,152 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrNoCertificates = errors.New("no PEM certificates found")
+
+type (
+	// Reloader serves the certificate, key and client CA bundle read from
+	// disk and swaps them when the files change, so rotated certificates are
+	// picked up without a restart.
+	Reloader struct {
+		certFile     string
+		keyFile      string
+		clientCAFile string
+
+		mu        sync.RWMutex
+		cert      *tls.Certificate
+		clientCAs *x509.CertPool
+		modTimes  map[string]time.Time
+	}
+)
+
+// NewReloader loads the files once and fails if they are not usable.
+// clientCAFile is optional and only needed for mutual TLS.
+func NewReloader(certFile, keyFile, clientCAFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, clientCAFile: clientCAFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads every file again. On error the previous certificates stay in use.
+func (r *Reloader) Reload() error {
+	modTimes, err := r.stat()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	var clientCAs *x509.CertPool
+	if r.clientCAFile != "" {
+		if clientCAs, err = LoadPool(r.clientCAFile); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+	return nil
+}
+
+// Watch checks the files every interval and reloads them when one changed,
+// until ctx is done.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if !r.changed() {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				slog.Error("tls certificates reload failed", "cert_file", r.certFile, "error", err)
+				continue
+			}
+			slog.Info("tls certificates reloaded", "cert_file", r.certFile)
+		}
+	}()
+}
+
+// TLSConfig returns base with the reloadable certificate and client CAs
+// plugged in. Each handshake gets the files loaded at that moment.
+func (r *Reloader) TLSConfig(base *tls.Config) *tls.Config {
+	cfg := base.Clone()
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		current := base.Clone()
+		current.Certificates = []tls.Certificate{*r.cert}
+		current.ClientCAs = r.clientCAs
+		return current, nil
+	}
+	return cfg
+}
+
+func (r *Reloader) changed() bool {
+	modTimes, err := r.stat()
+	if err != nil {
+		// Mientras se reemplazan los archivos puede faltar alguno.
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for path, modTime := range modTimes {
+		if !modTime.Equal(r.modTimes[path]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reloader) stat() (map[string]time.Time, error) {
+	modTimes := make(map[string]time.Time)
+	for _, path := range []string{r.certFile, r.keyFile, r.clientCAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[path] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// LoadPool reads a PEM bundle of CA certificates.
+func LoadPool(path string) (*x509.CertPool, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(raw) {
+		return nil, fmt.Errorf("%s: %w", path, ErrNoCertificates)
+	}
+	return pool, nil
+}
@@ -0,0 +1,227 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	ca := &testCA{cert: cert, key: key, dir: t.TempDir()}
+	writePEM(t, ca.path("ca.pem"), "CERTIFICATE", der)
+	return ca
+}
+
+func (ca *testCA) path(name string) string {
+	return filepath.Join(ca.dir, name)
+}
+
+// issue writes name.pem and name-key.pem, signed by ca, for localhost.
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = ca.path(name+".pem"), ca.path(name+"-key.pem")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, kind string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// replace copies src over dst and moves its modification time forward, as
+// a rotation would.
+func replace(t *testing.T, dst, src string, at time.Time) {
+	t.Helper()
+	raw, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(dst, at, at); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// servedName is the common name of the certificate the next handshake gets.
+func servedName(t *testing.T, r *Reloader) string {
+	t.Helper()
+	cfg, err := r.TLSConfig(&tls.Config{}).GetConfigForClient(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestWatchReloadsRotatedCertificates(t *testing.T) {
+	ca := newTestCA(t, "ca")
+	certFile, keyFile := ca.issue(t, "old", x509.ExtKeyUsageServerAuth)
+	newCert, newKey := ca.issue(t, "new", x509.ExtKeyUsageServerAuth)
+	r, err := NewReloader(certFile, keyFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.Watch(ctx, 5*time.Millisecond)
+
+	later := time.Now().Add(time.Minute)
+	replace(t, certFile, newCert, later)
+	replace(t, keyFile, newKey, later)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for servedName(t, r) != "new" {
+		if time.Now().After(deadline) {
+			t.Fatal("Watch() never served the rotated certificate")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestFailedReloadKeepsTheCertificate(t *testing.T) {
+	ca := newTestCA(t, "ca")
+	certFile, keyFile := ca.issue(t, "current", x509.ExtKeyUsageServerAuth)
+	_, otherKey := ca.issue(t, "other", x509.ExtKeyUsageServerAuth)
+	r, err := NewReloader(certFile, keyFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	broken := ca.path("broken.pem")
+	if err := os.WriteFile(broken, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		dst, src string
+	}{
+		{"garbage certificate", certFile, broken},
+		{"key of another certificate", keyFile, otherKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, _ := os.ReadFile(tt.dst)
+			defer os.WriteFile(tt.dst, raw, 0o600)
+
+			replace(t, tt.dst, tt.src, time.Now().Add(time.Minute))
+			if err := r.Reload(); err == nil {
+				t.Fatal("Reload() accepted a broken pair")
+			}
+			if name := servedName(t, r); name != "current" {
+				t.Fatalf("served %q after a failed reload, want the previous certificate", name)
+			}
+		})
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newTestCA(t, "ca")
+	stranger := newTestCA(t, "stranger")
+	serverCert, serverKey := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	r, err := NewReloader(serverCert, serverKey, ca.path("ca.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	server.TLS = r.TLSConfig(&tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, MinVersion: tls.VersionTLS12})
+	server.StartTLS()
+	defer server.Close()
+
+	trustedCert, trustedKey := ca.issue(t, "client", x509.ExtKeyUsageClientAuth)
+	strangerCert, strangerKey := stranger.issue(t, "client", x509.ExtKeyUsageClientAuth)
+	tests := []struct {
+		name          string
+		cert, key     string
+		wantConnected bool
+	}{
+		{"no client certificate", "", "", false},
+		{"certificate from another CA", strangerCert, strangerKey, false},
+		{"certificate from the client CA", trustedCert, trustedKey, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ClientTLS(ca.path("ca.pem"), tt.cert, tt.key, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+			resp, err := client.Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if connected := err == nil; connected != tt.wantConnected {
+				t.Fatalf("request error = %v, want connected %v", err, tt.wantConnected)
+			}
+		})
+	}
+}
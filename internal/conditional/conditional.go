@@ -0,0 +1,156 @@
+package conditional
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/defines"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/listing"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/upstream"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ETag is a strong validator over the JSON representation of v, the
+// whole response body.
+func ETag(v interface{}) (string, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// Validators makes the upstream reads of the request record their ETag and
+// Last-Modified, for Respond and CheckIfMatch to use. It goes right before
+// the handler, so calls made by earlier middlewares (token checks) do not
+// count as reads.
+func Validators() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		reqCtx, _ := upstream.WithValidators(ctx.Request.Context())
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+		ctx.Next()
+	}
+}
+
+// Respond writes data in the {"data","error"} envelope with validators, or
+// an empty 304 when the client copy is still current. When the response
+// comes from a single upstream read that sent an ETag or Last-Modified,
+// those are passed through; otherwise the ETag is computed from the body
+// and there is no Last-Modified, since a time kept by the gateway would
+// differ between replicas and restarts.
+func Respond(ctx *gin.Context, data interface{}) {
+	respond(ctx, envelope(data))
+}
+
+// RespondPage is Respond for a listing, adding its "meta".
+func RespondPage(ctx *gin.Context, data interface{}, page *listing.Page) {
+	body := envelope(data)
+	body["meta"] = page
+	respond(ctx, body)
+}
+
+func envelope(data interface{}) map[string]interface{} {
+	return map[string]interface{}{"data": data, "error": nil}
+}
+
+func respond(ctx *gin.Context, body map[string]interface{}) {
+	etag, lastModified, err := validators(ctx, body)
+	if err != nil {
+		utils.Response(ctx, http.StatusOK, body)
+		return
+	}
+	header := ctx.Writer.Header()
+	header.Set("ETag", etag)
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	// Se puede guardar, pero hay que revalidar antes de usarlo.
+	header.Set("Cache-Control", "no-cache")
+
+	if notModified(ctx.Request, etag, lastModified) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	utils.Response(ctx, http.StatusOK, body)
+}
+
+// validators prefers what the upstream sent for the read behind body.
+func validators(ctx *gin.Context, body map[string]interface{}) (string, time.Time, error) {
+	etag, rawLastModified := upstream.ValidatorsFrom(ctx.Request.Context()).Get()
+	lastModified, err := http.ParseTime(rawLastModified)
+	if err != nil {
+		lastModified = time.Time{}
+	}
+	if etag != "" {
+		return etag, lastModified, nil
+	}
+	etag, err = ETag(body)
+	return etag, lastModified, err
+}
+
+// CheckIfMatch answers 412 and returns false when the request carries an
+// If-Match that no longer matches current, the value just read for the
+// update, so the client does not overwrite a change it has not seen. The
+// ETag is the one Respond would send for current. The upstreams take no
+// precondition, so a write that lands between reading current and the
+// update is still overwritten; the check narrows that window but does not
+// close it.
+func CheckIfMatch(ctx *gin.Context, current interface{}) bool {
+	etag, _, err := validators(ctx, envelope(current))
+	if err != nil || !matches(ctx.GetHeader("If-Match"), etag, false) {
+		utils.ApiErrorResponse(ctx, utils.NewApiError(defines.PreconditionFailed, http.StatusPreconditionFailed))
+		return false
+	}
+	return true
+}
+
+// notModified applies If-None-Match to reads, or If-Modified-Since when
+// the request has no If-None-Match and the response has a Last-Modified.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		return matches(header, etag, true)
+	}
+	if lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	// Last-Modified solo tiene precisión de segundos.
+	return err == nil && !lastModified.Truncate(time.Second).After(since)
+}
+
+// matches compares etag with a list header such as `"a", W/"b"` or `*`.
+// If-None-Match uses the weak comparison and If-Match the strong one, where
+// a weak ETag never matches.
+func matches(header, etag string, weak bool) bool {
+	if strings.HasPrefix(etag, "W/") {
+		if !weak {
+			return strings.TrimSpace(header) == "*"
+		}
+		etag = strings.TrimPrefix(etag, "W/")
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package conditional
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/upstream"
+	"github.com/gin-gonic/gin"
+)
+
+var liquor = map[string]string{"_id": "l1", "name": "Mezcal"}
+
+// serve answers GET and PUT /liquor with liquor, as read from an upstream
+// that sent etag and lastModified (none when both are empty).
+func serve(t *testing.T, etag, lastModified string, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	eng := gin.New()
+	read := func(ctx *gin.Context) {
+		if etag != "" || lastModified != "" {
+			upstream.ValidatorsFrom(ctx.Request.Context()).Add(etag, lastModified)
+		}
+	}
+	eng.GET("/liquor", Validators(), func(ctx *gin.Context) {
+		read(ctx)
+		Respond(ctx, liquor)
+	})
+	eng.PUT("/liquor", Validators(), func(ctx *gin.Context) {
+		read(ctx)
+		if CheckIfMatch(ctx, liquor) {
+			ctx.Status(http.StatusNoContent)
+		}
+	})
+	rec := httptest.NewRecorder()
+	eng.ServeHTTP(rec, req)
+	return rec
+}
+
+func request(method string, headers ...string) *http.Request {
+	req := httptest.NewRequest(method, "/liquor", nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	return req
+}
+
+func TestRespondComputedETag(t *testing.T) {
+	rec := serve(t, "", "", request(http.MethodGet))
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("status %d, ETag %q", rec.Code, etag)
+	}
+	if got := rec.Header().Get("Last-Modified"); got != "" {
+		t.Fatalf("Last-Modified = %q without an upstream one", got)
+	}
+
+	tests := []struct {
+		ifNoneMatch string
+		want        int
+	}{
+		{etag, http.StatusNotModified},
+		{"W/" + etag, http.StatusNotModified},
+		{`"other", ` + etag, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+		{`"other"`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := serve(t, "", "", request(http.MethodGet, "If-None-Match", tt.ifNoneMatch))
+		if rec.Code != tt.want {
+			t.Errorf("If-None-Match %s: status = %d, want %d", tt.ifNoneMatch, rec.Code, tt.want)
+		}
+		if rec.Code == http.StatusNotModified && rec.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: 304 with a body", tt.ifNoneMatch)
+		}
+	}
+}
+
+func TestRespondUpstreamValidators(t *testing.T) {
+	const lastModified = "Tue, 01 Oct 2024 10:00:00 GMT"
+	rec := serve(t, `"v7"`, lastModified, request(http.MethodGet))
+	if got := rec.Header().Get("ETag"); got != `"v7"` {
+		t.Fatalf("ETag = %q, want the upstream one", got)
+	}
+	if got := rec.Header().Get("Last-Modified"); got != lastModified {
+		t.Fatalf("Last-Modified = %q, want %q", got, lastModified)
+	}
+
+	tests := []struct {
+		name    string
+		headers []string
+		want    int
+	}{
+		{"same time", []string{"If-Modified-Since", lastModified}, http.StatusNotModified},
+		{"later", []string{"If-Modified-Since", "Wed, 02 Oct 2024 10:00:00 GMT"}, http.StatusNotModified},
+		{"earlier", []string{"If-Modified-Since", "Tue, 01 Oct 2024 09:59:59 GMT"}, http.StatusOK},
+		{"not a date", []string{"If-Modified-Since", "yesterday"}, http.StatusOK},
+		{"If-None-Match wins", []string{"If-None-Match", `"v6"`, "If-Modified-Since", lastModified}, http.StatusOK},
+		{"upstream ETag", []string{"If-None-Match", `"v7"`}, http.StatusNotModified},
+	}
+	for _, tt := range tests {
+		rec := serve(t, `"v7"`, lastModified, request(http.MethodGet, tt.headers...))
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+
+	// Without a Last-Modified, If-Modified-Since is ignored.
+	rec = serve(t, `"v7"`, "", request(http.MethodGet, "If-Modified-Since", lastModified))
+	if rec.Code != http.StatusOK {
+		t.Errorf("If-Modified-Since without Last-Modified: status = %d", rec.Code)
+	}
+}
+
+func TestRespondWeakUpstreamETag(t *testing.T) {
+	if rec := serve(t, `W/"v7"`, "", request(http.MethodGet, "If-None-Match", `"v7"`)); rec.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match against a weak ETag: status = %d, want 304", rec.Code)
+	}
+	if rec := serve(t, `W/"v7"`, "", request(http.MethodPut, "If-Match", `W/"v7"`)); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("If-Match against a weak ETag: status = %d, want 412", rec.Code)
+	}
+	if rec := serve(t, `W/"v7"`, "", request(http.MethodPut, "If-Match", "*")); rec.Code != http.StatusNoContent {
+		t.Errorf("If-Match * against a weak ETag: status = %d, want 204", rec.Code)
+	}
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	computed := serve(t, "", "", request(http.MethodGet)).Header().Get("ETag")
+	tests := []struct {
+		name     string
+		upstream string
+		ifMatch  string
+		want     int
+	}{
+		{"computed ETag", "", computed, http.StatusNoContent},
+		{"one of a list", "", `"other", ` + computed, http.StatusNoContent},
+		{"stale ETag", "", `"other"`, http.StatusPreconditionFailed},
+		{"weak copy of the ETag", "", "W/" + computed, http.StatusPreconditionFailed},
+		{"upstream ETag", `"v7"`, `"v7"`, http.StatusNoContent},
+		{"computed ETag when the upstream sent one", `"v7"`, computed, http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		rec := serve(t, tt.upstream, "", request(http.MethodPut, "If-Match", tt.ifMatch))
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestValidatorsOfSeveralReads(t *testing.T) {
+	_, v := upstream.WithValidators(httptest.NewRequest(http.MethodGet, "/", nil).Context())
+	v.Add(`"a"`, "")
+	if etag, _ := v.Get(); etag != `"a"` {
+		t.Fatalf("Get() = %q after one read", etag)
+	}
+	v.Add(`"b"`, "")
+	if etag, _ := v.Get(); etag != "" {
+		t.Fatalf("Get() = %q after two reads, want none", etag)
+	}
+}
@@ -0,0 +1,517 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/cache"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/certs"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/cors"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/ratelimit"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/tokens"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/tracing"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/upstream"
+)
+
+type (
+	// Config is every setting of the gateway, loaded once at startup and
+	// handed to the packages that need it.
+	Config struct {
+		Server    Server
+		TLS       TLS
+		Upstreams Upstreams
+		APIKeys   APIKeys
+		Auth      tokens.Config
+		RateLimit RateLimit
+		Cache     Cache
+		Search    Search
+		CORS      cors.Config
+		Metrics   Metrics
+		Readiness Readiness
+		Tracing   tracing.Config
+		Features  Features
+		LogLevel  string
+	}
+
+	Server struct {
+		Port              string
+		ReadTimeout       time.Duration
+		ReadHeaderTimeout time.Duration
+		WriteTimeout      time.Duration
+		IdleTimeout       time.Duration
+		MaxHeaderBytes    int
+		// DrainDelay is how long /readyz fails before the listener closes;
+		// ShutdownTimeout bounds the wait for in-flight requests after that.
+		DrainDelay      time.Duration
+		ShutdownTimeout time.Duration
+	}
+
+	// TLS terminates HTTPS in the gateway when CertFile and KeyFile are set.
+	TLS struct {
+		CertFile string
+		KeyFile  string
+		// ClientCAFile verifies client certificates for mutual TLS.
+		ClientCAFile   string
+		ClientAuth     tls.ClientAuthType
+		MinVersion     uint16
+		HTTP2          bool
+		ReloadInterval time.Duration
+	}
+
+	Upstreams struct {
+		Catalog          upstream.Config
+		Auth             upstream.Config
+		AI               upstream.Config
+		ImageRecognition upstream.Config
+		Scrapping        upstream.Config
+		Posts            upstream.Config
+		// CatalogListQuery is set when the catalog service sorts and filters
+		// /liquors and /recipes itself.
+		CatalogListQuery bool
+	}
+
+	APIKeys struct {
+		// File is the registry file; Secrets is the plain VALID_API_KEYS list
+		// used when there is no file.
+		File    string
+		Secrets []string
+	}
+
+	RateLimit struct {
+		Enabled bool
+		Limits  map[ratelimit.Class]ratelimit.Limit
+	}
+
+	// Cache is the read-through cache of catalog reads.
+	Cache struct {
+		Enabled    bool
+		MaxEntries int
+		Liquors    cache.TTL
+		Recipes    cache.TTL
+	}
+
+	// Search is the in-memory index behind /search.
+	Search struct {
+		RefreshInterval time.Duration
+	}
+
+	Metrics struct {
+		// Addr serves /metrics on its own listener when set.
+		Addr string
+	}
+
+	Readiness struct {
+		Required []string
+		Timeout  time.Duration
+		// CacheTTL is how long a readiness report is reused before the
+		// upstreams are probed again.
+		CacheTTL time.Duration
+	}
+
+	// Features turns off groups of routes whose microservice is not deployed.
+	Features struct {
+		AI        bool
+		Scrapping bool
+		Search    bool
+		GraphiQL  bool
+	}
+
+	// ValidationError lists every problem found while loading, so a bad
+	// deploy can be fixed in one go.
+	ValidationError struct {
+		Problems []string
+	}
+)
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Load reads the configuration from the environment and, if CONFIG_FILE is
+// set, from that YAML or JSON file. Environment variables win over the file.
+func Load() (*Config, error) {
+	s := &source{}
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		file, err := readFile(path)
+		if err != nil {
+			return nil, &ValidationError{Problems: []string{fmt.Sprintf("CONFIG_FILE: %v", err)}}
+		}
+		s.file = file
+	}
+
+	breaker := upstream.BreakerConfig{
+		FailureThreshold: s.int("CIRCUIT_FAILURE_THRESHOLD", 0),
+		OpenTimeout:      s.duration("CIRCUIT_OPEN_TIMEOUT", 0),
+		HalfOpenRequests: s.int("CIRCUIT_HALF_OPEN_REQUESTS", 0),
+	}
+	upstreamConfig := func(name, prefix string, timeout time.Duration) upstream.Config {
+		return upstream.Config{
+			Name:       name,
+			BaseURL:    s.str(prefix+"_DOMAIN", ""),
+			Timeout:    s.duration(prefix+"_TIMEOUT", timeout),
+			HealthPath: s.str(prefix+"_HEALTH_PATH", ""),
+			Breaker:    breaker,
+			TLS: upstream.TLSConfig{
+				CAFile:     s.str(prefix+"_TLS_CA_FILE", ""),
+				CertFile:   s.str(prefix+"_TLS_CERT_FILE", ""),
+				KeyFile:    s.str(prefix+"_TLS_KEY_FILE", ""),
+				ServerName: s.str(prefix+"_TLS_SERVER_NAME", ""),
+			},
+		}
+	}
+
+	cfg := &Config{
+		Server: Server{
+			Port:              s.str("PORT", "8080"),
+			ReadTimeout:       s.duration("SERVER_READ_TIMEOUT", 30*time.Second),
+			ReadHeaderTimeout: s.duration("SERVER_READ_HEADER_TIMEOUT", 5*time.Second),
+			WriteTimeout:      s.duration("SERVER_WRITE_TIMEOUT", 60*time.Second),
+			IdleTimeout:       s.duration("SERVER_IDLE_TIMEOUT", 120*time.Second),
+			MaxHeaderBytes:    s.int("SERVER_MAX_HEADER_BYTES", 64<<10),
+			DrainDelay:        s.duration("SHUTDOWN_DRAIN_DELAY", 5*time.Second),
+			ShutdownTimeout:   s.duration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		},
+		TLS: TLS{
+			CertFile:       s.str("TLS_CERT_FILE", ""),
+			KeyFile:        s.str("TLS_KEY_FILE", ""),
+			ClientCAFile:   s.str("TLS_CLIENT_CA_FILE", ""),
+			ClientAuth:     s.clientAuth("TLS_CLIENT_AUTH"),
+			MinVersion:     s.tlsVersion("TLS_MIN_VERSION", tls.VersionTLS12),
+			HTTP2:          s.bool("HTTP2_ENABLED", true),
+			ReloadInterval: s.duration("TLS_RELOAD_INTERVAL", time.Minute),
+		},
+		Upstreams: Upstreams{
+			Catalog:          upstreamConfig(upstream.Catalog, "MS_CATALOG", 5*time.Second),
+			Auth:             upstreamConfig(upstream.Auth, "MS_AUTH", 5*time.Second),
+			AI:               upstreamConfig(upstream.AI, "MS_AI", 30*time.Second),
+			ImageRecognition: upstreamConfig(upstream.ImageRecognition, "MS_IMAGE_RECOGNITION", 30*time.Second),
+			Scrapping:        upstreamConfig(upstream.Scrapping, "MS_SCRAPPING", 15*time.Second),
+			CatalogListQuery: s.bool("MS_CATALOG_LIST_QUERY", false),
+		},
+		APIKeys: APIKeys{
+			File:    s.str("API_KEYS_FILE", ""),
+			Secrets: s.list("VALID_API_KEYS", nil),
+		},
+		Auth: tokens.Config{
+			Algorithm:      strings.ToUpper(s.str("AUTH_JWT_ALG", "")),
+			Secret:         s.str("AUTH_JWT_SECRET", ""),
+			JWKSFile:       s.str("AUTH_JWKS_FILE", ""),
+			JWKSPath:       s.str("AUTH_JWKS_PATH", "/.well-known/jwks.json"),
+			JWKSRefresh:    s.duration("AUTH_JWKS_REFRESH", 10*time.Minute),
+			Leeway:         s.duration("AUTH_JWT_LEEWAY", 30*time.Second),
+			RemoteFallback: s.bool("AUTH_REMOTE_VERIFY_FALLBACK", false),
+			CacheTTL:       s.duration("AUTH_VERIFY_CACHE_TTL", 30*time.Second),
+			SessionMaxAge:  s.duration("SESSION_MAX_AGE", 24*time.Hour),
+		},
+		RateLimit: RateLimit{
+			Enabled: s.bool("RATE_LIMIT_ENABLED", true),
+			Limits: map[ratelimit.Class]ratelimit.Limit{
+				ratelimit.Cheap:     s.limit("RATE_LIMIT_CHEAP", ratelimit.Limit{Requests: 300, Per: time.Minute}),
+				ratelimit.Expensive: s.limit("RATE_LIMIT_EXPENSIVE", ratelimit.Limit{Requests: 20, Per: time.Minute}),
+				ratelimit.Client:    s.limit("RATE_LIMIT_CLIENT", ratelimit.Limit{Requests: 600, Per: time.Minute}),
+			},
+		},
+		CORS: cors.Config{
+			AllowedOrigins:   s.list("CORS_ALLOWED_ORIGINS", []string{cors.Wildcard}),
+			AllowedMethods:   s.list("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+			AllowedHeaders:   s.list("CORS_ALLOWED_HEADERS", []string{"Content-Type", "Accept", "Accept-Encoding", "Authorization", "Cache-Control", "X-Requested-With", "X-CSRF-Token", "x-api-key", "x-auth-key", "x-auth-token", "X-Request-ID", "If-Match", "If-None-Match", "If-Modified-Since"}),
+			ExposedHeaders:   s.list("CORS_EXPOSED_HEADERS", []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", "X-Request-ID", "ETag", "Last-Modified"}),
+			AllowCredentials: s.bool("CORS_ALLOW_CREDENTIALS", false),
+			MaxAge:           s.duration("CORS_MAX_AGE", 10*time.Minute),
+		},
+		Cache: Cache{
+			Enabled:    s.bool("CACHE_ENABLED", true),
+			MaxEntries: s.int("CACHE_MAX_ENTRIES", 1000),
+			Liquors: cache.TTL{
+				Fresh: s.duration("CACHE_LIQUORS_TTL", 5*time.Minute),
+				Stale: s.duration("CACHE_LIQUORS_STALE_TTL", 10*time.Minute),
+			},
+			Recipes: cache.TTL{
+				Fresh: s.duration("CACHE_RECIPES_TTL", time.Minute),
+				Stale: s.duration("CACHE_RECIPES_STALE_TTL", 5*time.Minute),
+			},
+		},
+		Search: Search{
+			RefreshInterval: s.duration("SEARCH_REFRESH_INTERVAL", 5*time.Minute),
+		},
+		Metrics: Metrics{Addr: s.str("METRICS_ADDR", "")},
+		Readiness: Readiness{
+			Required: s.list("READYZ_REQUIRED", []string{upstream.Catalog, upstream.Auth}),
+			Timeout:  s.duration("READYZ_TIMEOUT", 2*time.Second),
+			CacheTTL: s.duration("READYZ_CACHE_TTL", 5*time.Second),
+		},
+		Tracing: tracing.Config{
+			Exporter:    strings.ToLower(s.str("OTEL_TRACES_EXPORTER", "none")),
+			ServiceName: s.str("OTEL_SERVICE_NAME", "cococtel-gateway"),
+		},
+		Features: Features{
+			AI:        s.bool("FEATURE_AI", true),
+			Scrapping: s.bool("FEATURE_SCRAPPING", true),
+			Search:    s.bool("FEATURE_SEARCH", true),
+			GraphiQL:  s.bool("FEATURE_GRAPHIQL", true),
+		},
+		LogLevel: strings.ToLower(s.str("LOG_LEVEL", "info")),
+	}
+	cfg.Upstreams.Posts = upstreamConfig(upstream.Posts, "MS_POSTS", 5*time.Second)
+	// Sin MS_POSTS_DOMAIN los posts siguen en el microservicio de catálogo, como antes.
+	if cfg.Upstreams.Posts.BaseURL == "" {
+		cfg.Upstreams.Posts.BaseURL = cfg.Upstreams.Catalog.BaseURL
+		if cfg.Upstreams.Posts.HealthPath == "" {
+			cfg.Upstreams.Posts.HealthPath = cfg.Upstreams.Catalog.HealthPath
+		}
+		if cfg.Upstreams.Posts.TLS.IsZero() {
+			cfg.Upstreams.Posts.TLS = cfg.Upstreams.Catalog.TLS
+		}
+	}
+
+	if cfg.Auth.Algorithm == "" {
+		switch {
+		case cfg.Auth.Secret != "":
+			cfg.Auth.Algorithm = tokens.HS256
+		case cfg.Auth.JWKSFile != "" || s.str("AUTH_JWKS_PATH", "") != "":
+			cfg.Auth.Algorithm = tokens.RS256
+		}
+	}
+
+	cfg.validate(s)
+	if len(s.problems) > 0 {
+		return nil, &ValidationError{Problems: s.problems}
+	}
+	return cfg, nil
+}
+
+// Enabled returns the upstreams in use with the current feature toggles.
+func (u Upstreams) Enabled(features Features) []upstream.Config {
+	enabled := []upstream.Config{u.Catalog, u.Auth}
+	if features.AI {
+		enabled = append(enabled, u.AI, u.ImageRecognition)
+	}
+	if features.Scrapping {
+		enabled = append(enabled, u.Scrapping)
+	}
+	return append(enabled, u.Posts)
+}
+
+func (cfg *Config) validate(s *source) {
+	domainKeys := map[string]string{
+		upstream.Catalog:          "MS_CATALOG_DOMAIN",
+		upstream.Auth:             "MS_AUTH_DOMAIN",
+		upstream.AI:               "MS_AI_DOMAIN",
+		upstream.ImageRecognition: "MS_IMAGE_RECOGNITION_DOMAIN",
+		upstream.Scrapping:        "MS_SCRAPPING_DOMAIN",
+		upstream.Posts:            "MS_POSTS_DOMAIN",
+	}
+	server := cfg.Server
+	for key, timeout := range map[string]time.Duration{
+		"SERVER_READ_TIMEOUT":        server.ReadTimeout,
+		"SERVER_READ_HEADER_TIMEOUT": server.ReadHeaderTimeout,
+		"SERVER_WRITE_TIMEOUT":       server.WriteTimeout,
+		"SERVER_IDLE_TIMEOUT":        server.IdleTimeout,
+		"SHUTDOWN_TIMEOUT":           server.ShutdownTimeout,
+	} {
+		if timeout <= 0 {
+			s.problem("%s must be positive", key)
+		}
+	}
+	if server.DrainDelay < 0 {
+		s.problem("SHUTDOWN_DRAIN_DELAY must not be negative")
+	}
+	if server.MaxHeaderBytes <= 0 {
+		s.problem("SERVER_MAX_HEADER_BYTES must be positive")
+	}
+
+	cfg.TLS.validate(s)
+
+	known := make(map[string]bool)
+	for _, up := range cfg.Upstreams.Enabled(cfg.Features) {
+		known[up.Name] = true
+		// Una respuesta que tarda más que el write timeout se corta a medias.
+		if server.WriteTimeout > 0 && up.Timeout >= server.WriteTimeout {
+			s.problem("SERVER_WRITE_TIMEOUT (%s) must be longer than the %s upstream timeout (%s)", server.WriteTimeout, up.Name, up.Timeout)
+		}
+		if up.Name == upstream.Posts && s.str("MS_POSTS_DOMAIN", "") == "" {
+			// Usa el dominio del catálogo, que ya se validó.
+			continue
+		}
+		key := domainKeys[up.Name]
+		if up.BaseURL == "" {
+			s.problem("%s is required", key)
+		} else if err := validURL(up.BaseURL); err != nil {
+			s.problem("%s: %v", key, err)
+		}
+		prefix := strings.TrimSuffix(key, "_DOMAIN")
+		if up.HealthPath != "" && !strings.HasPrefix(up.HealthPath, "/") {
+			s.problem("%s: health path must start with /", prefix+"_HEALTH_PATH")
+		}
+		validateUpstreamTLS(s, prefix, up)
+	}
+
+	if cfg.APIKeys.File == "" && len(cfg.APIKeys.Secrets) == 0 {
+		s.problem("API_KEYS_FILE or VALID_API_KEYS is required")
+	}
+
+	switch cfg.Auth.Algorithm {
+	case "":
+	case tokens.HS256:
+		if cfg.Auth.Secret == "" {
+			s.problem("AUTH_JWT_SECRET is required for %s", tokens.HS256)
+		}
+	case tokens.RS256:
+	default:
+		s.problem("AUTH_JWT_ALG: unsupported algorithm %q", cfg.Auth.Algorithm)
+	}
+
+	if cfg.Auth.SessionMaxAge <= 0 {
+		s.problem("SESSION_MAX_AGE must be positive")
+	}
+
+	for _, name := range cfg.Readiness.Required {
+		if !known[name] {
+			s.problem("READYZ_REQUIRED: unknown or disabled upstream %q", name)
+		}
+	}
+	if cfg.Cache.Enabled && cfg.Cache.MaxEntries <= 0 {
+		s.problem("CACHE_MAX_ENTRIES must be positive")
+	}
+	if cfg.Features.Search && cfg.Search.RefreshInterval <= 0 {
+		s.problem("SEARCH_REFRESH_INTERVAL must be positive")
+	}
+
+	if cfg.Readiness.Timeout <= 0 {
+		s.problem("READYZ_TIMEOUT must be positive")
+	}
+	if cfg.Readiness.CacheTTL < 0 {
+		s.problem("READYZ_CACHE_TTL must not be negative")
+	}
+
+	for _, origin := range cfg.CORS.AllowedOrigins {
+		if origin == cors.Wildcard {
+			if cfg.CORS.AllowCredentials {
+				s.problem("CORS_ALLOW_CREDENTIALS needs explicit CORS_ALLOWED_ORIGINS, not %q", cors.Wildcard)
+			}
+			continue
+		}
+		if _, err := cors.ParseOrigin(origin); err != nil {
+			s.problem("CORS_ALLOWED_ORIGINS: %v", err)
+		}
+	}
+
+	switch cfg.Tracing.Exporter {
+	case "none", "otlp", "stdout", "console":
+	default:
+		s.problem("OTEL_TRACES_EXPORTER: unsupported exporter %q", cfg.Tracing.Exporter)
+	}
+
+	switch cfg.LogLevel {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		s.problem("LOG_LEVEL: unsupported level %q", cfg.LogLevel)
+	}
+}
+
+func validateUpstreamTLS(s *source, prefix string, up upstream.Config) {
+	t := up.TLS
+	if t.IsZero() {
+		return
+	}
+	if strings.HasPrefix(up.BaseURL, "http://") {
+		s.problem("%s_TLS_*: %s_DOMAIN must use https", prefix, prefix)
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		s.problem("%s_TLS_CERT_FILE and %s_TLS_KEY_FILE must be set together", prefix, prefix)
+		return
+	}
+	// Se cargan aquí para que un PEM roto aparezca en el informe y no al arrancar el router.
+	if _, err := certs.ClientTLS(t.CAFile, t.CertFile, t.KeyFile, t.ServerName); err != nil {
+		s.problem("%s_TLS_*: %v", prefix, err)
+	}
+}
+
+// Enabled reports whether the gateway serves HTTPS.
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+func (t TLS) validate(s *source) {
+	if !t.Enabled() {
+		if t.ClientCAFile != "" || t.ClientAuth != tls.NoClientCert {
+			s.problem("TLS_CLIENT_CA_FILE and TLS_CLIENT_AUTH need TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		return
+	}
+	if t.CertFile == "" || t.KeyFile == "" {
+		s.problem("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	for key, path := range map[string]string{"TLS_CERT_FILE": t.CertFile, "TLS_KEY_FILE": t.KeyFile, "TLS_CLIENT_CA_FILE": t.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			s.problem("%s: %v", key, err)
+		}
+	}
+	if t.ClientAuth != tls.NoClientCert && t.ClientCAFile == "" {
+		s.problem("TLS_CLIENT_CA_FILE is required when TLS_CLIENT_AUTH is set")
+	}
+}
+
+// clientAuth reads "none", "optional" (verify the certificate if one is
+// sent) or "require".
+func (s *source) clientAuth(key string) tls.ClientAuthType {
+	switch raw := strings.ToLower(s.str(key, "none")); raw {
+	case "none":
+		return tls.NoClientCert
+	case "optional":
+		return tls.VerifyClientCertIfGiven
+	case "require":
+		return tls.RequireAndVerifyClientCert
+	default:
+		s.problem("%s: unsupported value %q (none, optional or require)", key, raw)
+		return tls.NoClientCert
+	}
+}
+
+func (s *source) tlsVersion(key string, fallback uint16) uint16 {
+	switch raw := s.str(key, ""); raw {
+	case "":
+		return fallback
+	case "1.2":
+		return tls.VersionTLS12
+	case "1.3":
+		return tls.VersionTLS13
+	default:
+		s.problem("%s: unsupported version %q (1.2 or 1.3)", key, raw)
+		return fallback
+	}
+}
+
+func (s *source) limit(key string, fallback ratelimit.Limit) ratelimit.Limit {
+	raw := s.str(key, "")
+	if raw == "" {
+		return fallback
+	}
+	limit, err := ratelimit.ParseLimit(raw)
+	if err != nil {
+		s.problem("%s: %v", key, err)
+		return fallback
+	}
+	return limit
+}
+
+func validURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q", raw)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("URL %q must use http or https", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("URL %q has no host", raw)
+	}
+	return nil
+}
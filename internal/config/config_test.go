@@ -0,0 +1,143 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/ratelimit"
+)
+
+// setRequired sets the minimum environment Load accepts.
+func setRequired(t *testing.T) {
+	t.Helper()
+	for key, value := range map[string]string{
+		"MS_CATALOG_DOMAIN":           "https://catalog.internal",
+		"MS_AUTH_DOMAIN":              "https://auth.internal",
+		"MS_AI_DOMAIN":                "https://ai.internal",
+		"MS_IMAGE_RECOGNITION_DOMAIN": "https://ocr.internal",
+		"MS_SCRAPPING_DOMAIN":         "https://scrapping.internal",
+		"VALID_API_KEYS":              "k1",
+		"CONFIG_FILE":                 "",
+	} {
+		t.Setenv(key, value)
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	setRequired(t)
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"port", cfg.Server.Port, "8080"},
+		{"write timeout", cfg.Server.WriteTimeout, 60 * time.Second},
+		{"shutdown timeout", cfg.Server.ShutdownTimeout, 30 * time.Second},
+		{"tls", cfg.TLS.Enabled(), false},
+		{"posts use the catalog domain", cfg.Upstreams.Posts.BaseURL, "https://catalog.internal"},
+		{"ai timeout", cfg.Upstreams.AI.Timeout, 30 * time.Second},
+		{"rate limit", cfg.RateLimit.Enabled, true},
+		{"expensive limit", cfg.RateLimit.Limits[ratelimit.Expensive], ratelimit.Limit{Requests: 20, Per: time.Minute}},
+		{"cache", cfg.Cache.Enabled, true},
+		{"readiness", cfg.Readiness.Required, []string{"catalog", "auth"}},
+		{"search refresh", cfg.Search.RefreshInterval, 5 * time.Minute},
+		{"session max age", cfg.Auth.SessionMaxAge, 24 * time.Hour},
+		{"features", cfg.Features, Features{AI: true, Scrapping: true, Search: true, GraphiQL: true}},
+		{"log level", cfg.LogLevel, "info"},
+	}
+	for _, check := range checks {
+		if !reflect.DeepEqual(check.got, check.want) {
+			t.Errorf("%s = %v, want %v", check.name, check.got, check.want)
+		}
+	}
+}
+
+func TestLoadParsesValues(t *testing.T) {
+	tests := []struct {
+		key, value string
+		get        func(*Config) interface{}
+		want       interface{}
+	}{
+		{"SHUTDOWN_TIMEOUT", "45s", func(c *Config) interface{} { return c.Server.ShutdownTimeout }, 45 * time.Second},
+		{"SHUTDOWN_TIMEOUT", "45", func(c *Config) interface{} { return c.Server.ShutdownTimeout }, 45 * time.Second},
+		{"SHUTDOWN_DRAIN_DELAY", "0", func(c *Config) interface{} { return c.Server.DrainDelay }, time.Duration(0)},
+		{"CACHE_ENABLED", "false", func(c *Config) interface{} { return c.Cache.Enabled }, false},
+		{"FEATURE_AI", "0", func(c *Config) interface{} { return c.Features.AI }, false},
+		{"HTTP2_ENABLED", "FALSE", func(c *Config) interface{} { return c.TLS.HTTP2 }, false},
+		{"READYZ_REQUIRED", " catalog, ,posts ", func(c *Config) interface{} { return c.Readiness.Required }, []string{"catalog", "posts"}},
+		{"RATE_LIMIT_CHEAP", "100/30s", func(c *Config) interface{} { return c.RateLimit.Limits[ratelimit.Cheap] }, ratelimit.Limit{Requests: 100, Per: 30 * time.Second}},
+		{"AUTH_JWT_SECRET", "s3cret", func(c *Config) interface{} { return c.Auth.Algorithm }, "HS256"},
+		{"SESSION_MAX_AGE", "12h", func(c *Config) interface{} { return c.Auth.SessionMaxAge }, 12 * time.Hour},
+		{"LOG_LEVEL", "DEBUG", func(c *Config) interface{} { return c.LogLevel }, "debug"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			setRequired(t)
+			t.Setenv(tt.key, tt.value)
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if got := tt.get(cfg); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("%s = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	setRequired(t)
+	t.Setenv("SEARCH_REFRESH_INTERVAL", "0")
+	t.Setenv("TLS_CERT_FILE", filepath.Join(t.TempDir(), "cert.pem"))
+	t.Setenv("SERVER_READ_TIMEOUT", "soon")
+	t.Setenv("FEATURE_SCRAPPING", "maybe")
+	t.Setenv("RATE_LIMIT_EXPENSIVE", "20")
+	t.Setenv("SESSION_MAX_AGE", "0s")
+
+	_, err := Load()
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("Load() error = %v, want a ValidationError", err)
+	}
+	for _, want := range []string{
+		"SEARCH_REFRESH_INTERVAL must be positive",
+		"TLS_CERT_FILE and TLS_KEY_FILE must be set together",
+		`SERVER_READ_TIMEOUT: invalid duration "soon"`,
+		`FEATURE_SCRAPPING: invalid boolean "maybe"`,
+		`RATE_LIMIT_EXPENSIVE: invalid rate limit "20"`,
+		"SESSION_MAX_AGE must be positive",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Load() error does not mention %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadFileUnderEnvironment(t *testing.T) {
+	setRequired(t)
+	path := filepath.Join(t.TempDir(), "gateway.yaml")
+	content := "PORT: 9090\nLOG_LEVEL: warn\nREADYZ_REQUIRED: [catalog]\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("LOG_LEVEL", "error")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Server.Port != "9090" || cfg.LogLevel != "error" || !reflect.DeepEqual(cfg.Readiness.Required, []string{"catalog"}) {
+		t.Fatalf("Load() = port %s, log level %s, required %v; want the file's port and list and the environment's level",
+			cfg.Server.Port, cfg.LogLevel, cfg.Readiness.Required)
+	}
+}
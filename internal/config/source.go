@@ -0,0 +1,127 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// source resolves a setting from the environment first and the optional
+// config file second, collecting every malformed value instead of stopping
+// at the first one.
+type source struct {
+	file     map[string]string
+	problems []string
+}
+
+// readFile loads a flat YAML or JSON map whose keys are the same names as
+// the environment variables, e.g. MS_CATALOG_DOMAIN: https://catalog.
+func readFile(path string) (map[string]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &values)
+	default:
+		err = json.Unmarshal(raw, &values)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	file := make(map[string]string, len(values))
+	for key, value := range values {
+		switch v := value.(type) {
+		case nil:
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			file[key] = strings.Join(items, ",")
+		default:
+			file[key] = fmt.Sprint(v)
+		}
+	}
+	return file, nil
+}
+
+func (s *source) problem(format string, args ...interface{}) {
+	s.problems = append(s.problems, fmt.Sprintf(format, args...))
+}
+
+func (s *source) str(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	if v, ok := s.file[key]; ok && v != "" {
+		return v
+	}
+	return fallback
+}
+
+// duration accepts Go durations ("5s") or whole seconds ("5").
+func (s *source) duration(key string, fallback time.Duration) time.Duration {
+	raw := s.str(key, "")
+	if raw == "" {
+		return fallback
+	}
+	if d, err := time.ParseDuration(raw); err == nil && d >= 0 {
+		return d
+	}
+	if secs, err := strconv.Atoi(raw); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	s.problem("%s: invalid duration %q", key, raw)
+	return fallback
+}
+
+func (s *source) int(key string, fallback int) int {
+	raw := s.str(key, "")
+	if raw == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		s.problem("%s: invalid number %q", key, raw)
+		return fallback
+	}
+	return n
+}
+
+func (s *source) bool(key string, fallback bool) bool {
+	raw := s.str(key, "")
+	if raw == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		s.problem("%s: invalid boolean %q", key, raw)
+		return fallback
+	}
+	return b
+}
+
+// list reads comma-separated values, dropping empty items.
+func (s *source) list(key string, fallback []string) []string {
+	raw := s.str(key, "")
+	if raw == "" {
+		return fallback
+	}
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -34,12 +34,9 @@ func (a *authController) Verify() gin.HandlerFunc {
 			return
 		}
 
-		apiErr := a.authService.Verify(token)
+		apiErr := a.authService.Verify(ctx.Request.Context(), token)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data":  nil,
-				"error": map[string]interface{}{"message": apiErr.Message().Error(), "status": apiErr.Status()},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
 
@@ -61,12 +58,9 @@ func (a *authController) Register() gin.HandlerFunc {
 			return
 		}
 
-		newUser, apiErr := a.authService.Register(user)
+		newUser, apiErr := a.authService.Register(ctx.Request.Context(), user)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data":  nil,
-				"error": map[string]interface{}{"message": apiErr.Message().Error(), "status": apiErr.Status()},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
 
@@ -88,12 +82,9 @@ func (a *authController) Login() gin.HandlerFunc {
 			return
 		}
 
-		loginResponse, apiErr := a.authService.Login(credentails)
+		loginResponse, apiErr := a.authService.Login(ctx.Request.Context(), credentails)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data":  nil,
-				"error": map[string]interface{}{"message": apiErr.Message().Error(), "status": apiErr.Status()},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
 
@@ -33,12 +33,9 @@ func (ai *aiController) ProcessStrings() gin.HandlerFunc {
 			return
 		}
 
-		result, apiErr := ai.aiService.ProcessStrings(input)
+		result, apiErr := ai.aiService.ProcessStrings(ctx.Request.Context(), input)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data":  nil,
-				"error": map[string]interface{}{"message": apiErr.Message().Error(), "status": apiErr.Status()},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
 
@@ -60,12 +57,9 @@ func (ai *aiController) CreateRecipe() gin.HandlerFunc {
 			return
 		}
 
-		recipe, apiErr := ai.aiService.CreateRecipe(liquor)
+		recipe, apiErr := ai.aiService.CreateRecipe(ctx.Request.Context(), liquor)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data":  nil,
-				"error": map[string]interface{}{"message": apiErr.Message().Error(), "status": apiErr.Status()},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
 
@@ -103,15 +97,9 @@ func (ai *aiController) ExtractText() gin.HandlerFunc {
 			return
 		}
 
-		texts, apiErr := ai.aiService.ExtractTextFromImage(imageBytes)
+		texts, apiErr := ai.aiService.ExtractTextFromImage(ctx.Request.Context(), imageBytes)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data": nil,
-				"error": map[string]interface{}{
-					"message": apiErr.Message().Error(),
-					"status":  apiErr.Status(),
-				},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
 
@@ -1,7 +1,10 @@
 package catalogcontroller
 
 import (
+	"github.com/Cococtel/Cococtel_Gagateway/internal/cache"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/conditional"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/dtos"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/listing"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/services/catalogservice"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
 	"github.com/gin-gonic/gin"
@@ -33,38 +36,29 @@ func NewLiquorController(service catalogservice.ICatalog) ICatalog {
 
 func (c *catalogController) GetLiquors() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		liquors, apiErr := c.catalogService.GetLiquors()
-
+		q, err := listing.FromValues(ctx.Request.URL.Query())
+		if err != nil {
+			utils.ApiErrorResponse(ctx, utils.NewApiError(err, http.StatusBadRequest))
+			return
+		}
+		liquors, page, apiErr := c.catalogService.ListLiquors(ctx.Request.Context(), q)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data":  nil,
-				"error": map[string]interface{}{"message": apiErr.Message().Error(), "status": apiErr.Status()},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
-
-		utils.Response(ctx, http.StatusOK, map[string]interface{}{
-			"data":  liquors,
-			"error": nil,
-		})
+		conditional.RespondPage(ctx, liquors, page)
 	}
 }
 
 func (c *catalogController) GetLiquorByID() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		id := ctx.Param("id")
-		liquor, apiErr := c.catalogService.GetLiquorByID(id)
+		liquor, apiErr := c.catalogService.GetLiquorByID(ctx.Request.Context(), id)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data":  nil,
-				"error": map[string]interface{}{"message": apiErr.Message().Error(), "status": apiErr.Status()},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
-		utils.Response(ctx, http.StatusOK, map[string]interface{}{
-			"data":  liquor,
-			"error": nil,
-		})
+		conditional.Respond(ctx, liquor)
 	}
 }
 
@@ -79,12 +73,9 @@ func (c *catalogController) CreateLiquor() gin.HandlerFunc {
 			return
 		}
 
-		newLiquor, apiErr := c.catalogService.CreateLiquor(liquor)
+		newLiquor, apiErr := c.catalogService.CreateLiquor(ctx.Request.Context(), liquor)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data":  nil,
-				"error": map[string]interface{}{"message": apiErr.Message().Error(), "status": apiErr.Status()},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
 
@@ -107,12 +98,21 @@ func (c *catalogController) UpdateLiquor() gin.HandlerFunc {
 			return
 		}
 
-		updatedLiquor, apiErr := c.catalogService.UpdateLiquor(id, updates)
+		if ctx.GetHeader("If-Match") != "" {
+			// Se compara con lo que hay en el upstream, no con la caché.
+			current, apiErr := c.catalogService.GetLiquorByID(cache.Bypass(ctx.Request.Context()), id)
+			if apiErr != nil {
+				utils.ApiErrorResponse(ctx, apiErr)
+				return
+			}
+			if !conditional.CheckIfMatch(ctx, current) {
+				return
+			}
+		}
+
+		updatedLiquor, apiErr := c.catalogService.UpdateLiquor(ctx.Request.Context(), id, updates)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data":  nil,
-				"error": map[string]interface{}{"message": apiErr.Message().Error(), "status": apiErr.Status()},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
 
@@ -127,12 +127,9 @@ func (c *catalogController) DeleteLiquor() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		id := ctx.Param("id")
 
-		apiErr := c.catalogService.DeleteLiquor(id)
+		apiErr := c.catalogService.DeleteLiquor(ctx.Request.Context(), id)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data":  nil,
-				"error": map[string]interface{}{"message": apiErr.Message().Error(), "status": apiErr.Status()},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
 
@@ -145,36 +142,29 @@ func (c *catalogController) DeleteLiquor() gin.HandlerFunc {
 
 func (c *catalogController) GetRecipes() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		recipes, apiErr := c.catalogService.GetRecipes()
+		q, err := listing.FromValues(ctx.Request.URL.Query())
+		if err != nil {
+			utils.ApiErrorResponse(ctx, utils.NewApiError(err, http.StatusBadRequest))
+			return
+		}
+		recipes, page, apiErr := c.catalogService.ListRecipes(ctx.Request.Context(), q)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data":  nil,
-				"error": map[string]interface{}{"message": apiErr.Message().Error(), "status": apiErr.Status()},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
-		utils.Response(ctx, http.StatusOK, map[string]interface{}{
-			"data":  recipes,
-			"error": nil,
-		})
+		conditional.RespondPage(ctx, recipes, page)
 	}
 }
 
 func (c *catalogController) GetRecipeByID() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		id := ctx.Param("id")
-		recipe, apiErr := c.catalogService.GetRecipeByID(id)
+		recipe, apiErr := c.catalogService.GetRecipeByID(ctx.Request.Context(), id)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data":  nil,
-				"error": map[string]interface{}{"message": apiErr.Message().Error(), "status": apiErr.Status()},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
-		utils.Response(ctx, http.StatusOK, map[string]interface{}{
-			"data":  recipe,
-			"error": nil,
-		})
+		conditional.Respond(ctx, recipe)
 	}
 }
 
@@ -189,12 +179,9 @@ func (c *catalogController) CreateRecipe() gin.HandlerFunc {
 			return
 		}
 
-		newRecipe, apiErr := c.catalogService.CreateRecipe(recipe)
+		newRecipe, apiErr := c.catalogService.CreateRecipe(ctx.Request.Context(), recipe)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data":  nil,
-				"error": map[string]interface{}{"message": apiErr.Message().Error(), "status": apiErr.Status()},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
 
@@ -218,12 +205,21 @@ func (c *catalogController) UpdateRecipe() gin.HandlerFunc {
 			return
 		}
 
-		updatedRecipe, apiErr := c.catalogService.UpdateRecipe(id, updates)
+		if ctx.GetHeader("If-Match") != "" {
+			// Se compara con lo que hay en el upstream, no con la caché.
+			current, apiErr := c.catalogService.GetRecipeByID(cache.Bypass(ctx.Request.Context()), id)
+			if apiErr != nil {
+				utils.ApiErrorResponse(ctx, apiErr)
+				return
+			}
+			if !conditional.CheckIfMatch(ctx, current) {
+				return
+			}
+		}
+
+		updatedRecipe, apiErr := c.catalogService.UpdateRecipe(ctx.Request.Context(), id, updates)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data":  nil,
-				"error": map[string]interface{}{"message": apiErr.Message().Error(), "status": apiErr.Status()},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
 
@@ -238,12 +234,9 @@ func (c *catalogController) DeleteRecipe() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		id := ctx.Param("id")
 
-		apiErr := c.catalogService.DeleteRecipe(id)
+		apiErr := c.catalogService.DeleteRecipe(ctx.Request.Context(), id)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data":  nil,
-				"error": map[string]interface{}{"message": apiErr.Message().Error(), "status": apiErr.Status()},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
 
@@ -24,12 +24,9 @@ func (s *scrappingController) GetProductByCode() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		code := ctx.Param("code")
 
-		product, apiErr := s.aiService.GetProductByCode(code)
+		product, apiErr := s.aiService.GetProductByCode(ctx.Request.Context(), code)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data":  nil,
-				"error": map[string]interface{}{"message": apiErr.Message().Error(), "status": apiErr.Status()},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
 
@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"github.com/Cococtel/Cococtel_Gagateway/internal/health"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// Healthz only tells that the process is serving requests.
+func Healthz() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		utils.Success(c, http.StatusOK, gin.H{"status": health.StatusUp})
+	}
+}
+
+// Readyz probes the upstreams and answers 503 when a required one is down.
+func Readyz(checker *health.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report, ready := checker.Run(c.Request.Context())
+		status := http.StatusOK
+		if !ready {
+			status = http.StatusServiceUnavailable
+		}
+		utils.Success(c, status, report)
+	}
+}
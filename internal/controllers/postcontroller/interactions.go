@@ -0,0 +1,69 @@
+package postcontroller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/dtos"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+var errInvalidInput = errors.New("invalid input")
+
+func (c *PostsController) Like() gin.HandlerFunc {
+	return interaction(http.StatusOK, func(ctx *gin.Context) (*entities.Post, utils.ApiError) {
+		return c.postsService.Like(ctx.Request.Context(), ctx.Param("id"))
+	})
+}
+
+func (c *PostsController) Unlike() gin.HandlerFunc {
+	return interaction(http.StatusOK, func(ctx *gin.Context) (*entities.Post, utils.ApiError) {
+		return c.postsService.Unlike(ctx.Request.Context(), ctx.Param("id"))
+	})
+}
+
+func (c *PostsController) Comment() gin.HandlerFunc {
+	return interaction(http.StatusCreated, func(ctx *gin.Context) (*entities.Post, utils.ApiError) {
+		var comment dtos.Comment
+		if err := ctx.ShouldBindJSON(&comment); err != nil {
+			return nil, utils.NewApiError(errInvalidInput, http.StatusBadRequest)
+		}
+		return c.postsService.Comment(ctx.Request.Context(), ctx.Param("id"), comment.Value)
+	})
+}
+
+// DeleteComment borra el comentario identificado por su createdAt.
+func (c *PostsController) DeleteComment() gin.HandlerFunc {
+	return interaction(http.StatusOK, func(ctx *gin.Context) (*entities.Post, utils.ApiError) {
+		return c.postsService.DeleteComment(ctx.Request.Context(), ctx.Param("id"), ctx.Param("createdAt"))
+	})
+}
+
+func (c *PostsController) React() gin.HandlerFunc {
+	return interaction(http.StatusOK, func(ctx *gin.Context) (*entities.Post, utils.ApiError) {
+		return c.postsService.React(ctx.Request.Context(), ctx.Param("id"), ctx.Param("reaction"))
+	})
+}
+
+func (c *PostsController) Unreact() gin.HandlerFunc {
+	return interaction(http.StatusOK, func(ctx *gin.Context) (*entities.Post, utils.ApiError) {
+		return c.postsService.Unreact(ctx.Request.Context(), ctx.Param("id"), ctx.Param("reaction"))
+	})
+}
+
+// interaction answers with the post returned by apply, or with its error.
+func interaction(status int, apply func(ctx *gin.Context) (*entities.Post, utils.ApiError)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		post, apiErr := apply(ctx)
+		if apiErr != nil {
+			utils.ApiErrorResponse(ctx, apiErr)
+			return
+		}
+		utils.Response(ctx, status, map[string]interface{}{
+			"data":  post,
+			"error": nil,
+		})
+	}
+}
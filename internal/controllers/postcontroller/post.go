@@ -1,7 +1,8 @@
 package postcontroller
 
 import (
-	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/conditional"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/dtos"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/services/postservice"
 	"net/http"
 
@@ -19,48 +20,30 @@ func NewPostsController(service postservice.PostsService) *PostsController {
 
 func (c *PostsController) GetPosts() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		posts, apiErr := c.postsService.GetPosts()
+		posts, apiErr := c.postsService.GetPosts(ctx.Request.Context())
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data": nil,
-				"error": map[string]interface{}{
-					"message": apiErr.Message().Error(),
-					"status":  apiErr.Status(),
-				},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
-		utils.Response(ctx, http.StatusOK, map[string]interface{}{
-			"data":  posts,
-			"error": nil,
-		})
+		conditional.Respond(ctx, posts)
 	}
 }
 
 func (c *PostsController) GetPostByID() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		id := ctx.Param("id")
-		post, apiErr := c.postsService.GetPostByID(id)
+		post, apiErr := c.postsService.GetPostByID(ctx.Request.Context(), id)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data": nil,
-				"error": map[string]interface{}{
-					"message": apiErr.Message().Error(),
-					"status":  apiErr.Status(),
-				},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
-		utils.Response(ctx, http.StatusOK, map[string]interface{}{
-			"data":  post,
-			"error": nil,
-		})
+		conditional.Respond(ctx, post)
 	}
 }
 
 func (c *PostsController) CreatePost() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		var post entities.Post
+		var post dtos.Post
 		if err := ctx.ShouldBindJSON(&post); err != nil {
 			utils.Response(ctx, http.StatusBadRequest, map[string]interface{}{
 				"data": nil,
@@ -71,15 +54,9 @@ func (c *PostsController) CreatePost() gin.HandlerFunc {
 			})
 			return
 		}
-		newPost, apiErr := c.postsService.CreatePost(post)
+		newPost, apiErr := c.postsService.CreatePost(ctx.Request.Context(), post)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data": nil,
-				"error": map[string]interface{}{
-					"message": apiErr.Message().Error(),
-					"status":  apiErr.Status(),
-				},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
 		utils.Response(ctx, http.StatusCreated, map[string]interface{}{
@@ -103,15 +80,20 @@ func (c *PostsController) UpdatePost() gin.HandlerFunc {
 			})
 			return
 		}
-		updatedPost, apiErr := c.postsService.UpdatePost(id, updates)
+		if ctx.GetHeader("If-Match") != "" {
+			current, apiErr := c.postsService.GetPostByID(ctx.Request.Context(), id)
+			if apiErr != nil {
+				utils.ApiErrorResponse(ctx, apiErr)
+				return
+			}
+			if !conditional.CheckIfMatch(ctx, current) {
+				return
+			}
+		}
+
+		updatedPost, apiErr := c.postsService.UpdatePost(ctx.Request.Context(), id, updates)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data": nil,
-				"error": map[string]interface{}{
-					"message": apiErr.Message().Error(),
-					"status":  apiErr.Status(),
-				},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
 		utils.Response(ctx, http.StatusOK, map[string]interface{}{
@@ -124,15 +106,9 @@ func (c *PostsController) UpdatePost() gin.HandlerFunc {
 func (c *PostsController) DeletePost() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		id := ctx.Param("id")
-		apiErr := c.postsService.DeletePost(id)
+		apiErr := c.postsService.DeletePost(ctx.Request.Context(), id)
 		if apiErr != nil {
-			utils.Response(ctx, apiErr.Status(), map[string]interface{}{
-				"data": nil,
-				"error": map[string]interface{}{
-					"message": apiErr.Message().Error(),
-					"status":  apiErr.Status(),
-				},
-			})
+			utils.ApiErrorResponse(ctx, apiErr)
 			return
 		}
 		utils.Response(ctx, http.StatusOK, map[string]interface{}{
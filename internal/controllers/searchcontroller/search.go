@@ -0,0 +1,59 @@
+package searchcontroller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/services/searchservice"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	ISearch interface {
+		Search() gin.HandlerFunc
+	}
+
+	searchController struct {
+		searchService searchservice.ISearch
+	}
+)
+
+func NewSearchController(service searchservice.ISearch) ISearch {
+	return &searchController{searchService: service}
+}
+
+// Search answers GET /search?q=...&type=liquor,recipe&limit=20 with the
+// matches of every type ranked together.
+func (c *searchController) Search() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var kinds []string
+		for _, value := range ctx.QueryArray("type") {
+			for _, kind := range strings.Split(value, ",") {
+				if kind = strings.TrimSpace(kind); kind != "" {
+					kinds = append(kinds, kind)
+				}
+			}
+		}
+		limit := 0
+		if raw := ctx.Query("limit"); raw != "" {
+			var err error
+			if limit, err = strconv.Atoi(raw); err != nil {
+				utils.ApiErrorResponse(ctx, utils.NewApiError(fmt.Errorf("limit must be an integer, got %q", raw), http.StatusBadRequest))
+				return
+			}
+		}
+
+		results, apiErr := c.searchService.Search(ctx.Request.Context(), ctx.Query("q"), kinds, limit)
+		if apiErr != nil {
+			utils.ApiErrorResponse(ctx, apiErr)
+			return
+		}
+		utils.Response(ctx, http.StatusOK, map[string]interface{}{
+			"data":  results,
+			"error": nil,
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package controllers
+
+import (
+	"github.com/Cococtel/Cococtel_Gagateway/internal/upstream"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+func Circuits(clients []*upstream.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		statuses := make([]upstream.BreakerStatus, 0, len(clients))
+		for _, client := range clients {
+			statuses = append(statuses, client.Breaker().Status())
+		}
+		utils.Success(c, http.StatusOK, statuses)
+	}
+}
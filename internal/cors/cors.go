@@ -0,0 +1,129 @@
+package cors
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const Wildcard = "*"
+
+type (
+	Config struct {
+		// AllowedOrigins holds "*", exact origins (https://cococtel.app) or
+		// wildcard subdomains (https://*.cococtel.app).
+		AllowedOrigins []string
+		AllowedMethods []string
+		AllowedHeaders []string
+		ExposedHeaders []string
+		// AllowCredentials is never combined with "*": the origin is echoed.
+		AllowCredentials bool
+		MaxAge           time.Duration
+	}
+
+	// Policy is a Config with the origins parsed, ready to match requests.
+	Policy struct {
+		Config
+		anyOrigin bool
+		exact     map[string]bool
+		suffixes  []originPattern
+		also      func(origin string) bool
+	}
+
+	// originPattern is https://*.example.com split so that only subdomains
+	// with the same scheme and port match.
+	originPattern struct {
+		scheme string
+		suffix string
+		port   string
+	}
+)
+
+func NewPolicy(cfg Config) (*Policy, error) {
+	p := &Policy{Config: cfg, exact: make(map[string]bool)}
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == Wildcard {
+			p.anyOrigin = true
+			continue
+		}
+		u, err := ParseOrigin(origin)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(u.Hostname(), "*.") {
+			p.suffixes = append(p.suffixes, originPattern{
+				scheme: u.Scheme,
+				suffix: strings.ToLower(strings.TrimPrefix(u.Hostname(), "*")),
+				port:   u.Port(),
+			})
+			continue
+		}
+		p.exact[strings.ToLower(u.String())] = true
+	}
+	if p.anyOrigin && cfg.AllowCredentials {
+		return nil, fmt.Errorf("credentials cannot be allowed for origin %q", Wildcard)
+	}
+	return p, nil
+}
+
+// ParseOrigin checks that origin is scheme://host[:port] with nothing else,
+// allowing a leading "*." in the host. A trailing "/" is dropped, since
+// browsers never send it in the Origin header.
+func ParseOrigin(origin string) (*url.URL, error) {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid origin %q", origin)
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return nil, fmt.Errorf("origin %q must not have a path, query or credentials", origin)
+	}
+	if strings.Contains(strings.TrimPrefix(u.Hostname(), "*."), "*") {
+		return nil, fmt.Errorf("origin %q: only a leading *. wildcard is supported", origin)
+	}
+	u.Path = ""
+	return u, nil
+}
+
+// AlsoAllow makes the policy accept the origins allow reports, besides the
+// configured ones. It must be called before the policy is in use.
+func (p *Policy) AlsoAllow(allow func(origin string) bool) {
+	p.also = allow
+}
+
+// AllowOrigin reports whether the browser origin may call the API.
+func (p *Policy) AllowOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if p.anyOrigin || p.exact[strings.ToLower(origin)] {
+		return true
+	}
+	if p.also != nil && p.also(origin) {
+		return true
+	}
+	if len(p.suffixes) == 0 {
+		return false
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range p.suffixes {
+		if u.Scheme == pattern.scheme && u.Port() == pattern.port &&
+			strings.HasSuffix(host, pattern.suffix) && len(host) > len(pattern.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowOriginValue is what goes in Access-Control-Allow-Origin: "*" when
+// any origin is allowed, and the request origin otherwise.
+func (p *Policy) AllowOriginValue(origin string) string {
+	if p.anyOrigin {
+		return Wildcard
+	}
+	return origin
+}
@@ -0,0 +1,96 @@
+package cors
+
+import (
+	"testing"
+)
+
+func TestAllowOrigin(t *testing.T) {
+	policy, err := NewPolicy(Config{AllowedOrigins: []string{
+		"https://cococtel.app",
+		"https://*.cococtel.app",
+		"http://localhost:3000",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allowed := []string{
+		"https://cococtel.app",
+		"https://COCOCTEL.app",
+		"https://admin.cococtel.app",
+		"https://a.b.cococtel.app",
+		"http://localhost:3000",
+	}
+	for _, origin := range allowed {
+		if !policy.AllowOrigin(origin) {
+			t.Errorf("AllowOrigin(%q) = false, want true", origin)
+		}
+	}
+
+	denied := []string{
+		"",
+		"https://.cococtel.app",
+		"http://admin.cococtel.app",
+		"https://admin.cococtel.app:8443",
+		"https://evilcococtel.app",
+		"http://localhost:3001",
+		"https://evil.example",
+	}
+	for _, origin := range denied {
+		if policy.AllowOrigin(origin) {
+			t.Errorf("AllowOrigin(%q) = true, want false", origin)
+		}
+	}
+}
+
+func TestNewPolicyRejects(t *testing.T) {
+	invalid := map[string]Config{
+		"wildcard with credentials": {AllowedOrigins: []string{Wildcard}, AllowCredentials: true},
+		"no scheme":                 {AllowedOrigins: []string{"cococtel.app"}},
+		"path":                      {AllowedOrigins: []string{"https://cococtel.app/api"}},
+		"query":                     {AllowedOrigins: []string{"https://cococtel.app?x=1"}},
+		"wildcard in the middle":    {AllowedOrigins: []string{"https://api.*.cococtel.app"}},
+		"ftp":                       {AllowedOrigins: []string{"ftp://cococtel.app"}},
+	}
+	for name, cfg := range invalid {
+		if _, err := NewPolicy(cfg); err == nil {
+			t.Errorf("%s: NewPolicy() accepted %v", name, cfg.AllowedOrigins)
+		}
+	}
+
+	if _, err := NewPolicy(Config{AllowedOrigins: []string{"https://cococtel.app"}, AllowCredentials: true}); err != nil {
+		t.Errorf("credentials with an exact origin: %v", err)
+	}
+}
+
+func TestAllowOriginValue(t *testing.T) {
+	anyOrigin, _ := NewPolicy(Config{AllowedOrigins: []string{Wildcard}})
+	if got := anyOrigin.AllowOriginValue("https://cococtel.app"); got != Wildcard {
+		t.Errorf("AllowOriginValue() = %q, want %q", got, Wildcard)
+	}
+	exact, _ := NewPolicy(Config{AllowedOrigins: []string{"https://cococtel.app"}})
+	if got := exact.AllowOriginValue("https://cococtel.app"); got != "https://cococtel.app" {
+		t.Errorf("AllowOriginValue() = %q, want the request origin", got)
+	}
+}
+
+func TestAllowOriginTrailingSlashAndAlsoAllow(t *testing.T) {
+	policy, err := NewPolicy(Config{AllowedOrigins: []string{"https://cococtel.app/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !policy.AllowOrigin("https://cococtel.app") {
+		t.Error("an origin configured with a trailing / does not match the browser origin")
+	}
+
+	if policy.AllowOrigin("https://partner.example") {
+		t.Fatal("partner origin allowed before AlsoAllow")
+	}
+	policy.AlsoAllow(func(origin string) bool { return origin == "https://partner.example" })
+	if !policy.AllowOrigin("https://partner.example") {
+		t.Error("AllowOrigin() ignores AlsoAllow")
+	}
+	if policy.AllowOrigin("https://evil.example") {
+		t.Error("AlsoAllow opened other origins")
+	}
+}
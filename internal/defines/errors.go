@@ -3,5 +3,13 @@ package defines
 import "errors"
 
 var (
-	InvalidApiKey = errors.New("Api key invalida")
+	InvalidApiKey    = errors.New("Api key invalida")
+	ExpiredApiKey    = errors.New("Api key expirada")
+	ApiKeyScope      = errors.New("Api key sin permiso para esta ruta")
+	OriginNotAllowed = errors.New("Origen no permitido para esta api key")
+	MissingUserToken = errors.New("missing user token")
+	RateLimited      = errors.New("rate limit exceeded")
+	BodyTooLarge     = errors.New("request body too large")
+	// If-Match ya no coincide: otro cliente modificó el recurso.
+	PreconditionFailed = errors.New("resource was modified since it was read")
 )
@@ -1,3 +1,8 @@
 package defines
 
-const ()
+const (
+	// Clave del gin.Context donde RequireAuth guarda al usuario autenticado.
+	IdentityKey = "identity"
+	// Clave del gin.Context donde ValidateAPIKey guarda la api key usada.
+	APIKeyKey = "api_key"
+)
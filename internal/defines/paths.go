@@ -3,4 +3,11 @@ package defines
 const (
 	//Ping & consts
 	PingPath = "/ping"
+
+	//Status
+	CircuitsPath = "/status/circuits"
+
+	//Probes, sin api key
+	HealthzPath = "/healthz"
+	ReadyzPath  = "/readyz"
 )
@@ -3,8 +3,12 @@ package dtos
 type (
 	Post struct {
 		UrlImage string `json:"urlImage"`
-		Title    string `json:"title"`
-		Content  string `json:"content"`
+		Title    string `json:"title" binding:"required"`
+		Content  string `json:"content" binding:"required"`
 		Author   string `json:"author"`
 	}
+
+	Comment struct {
+		Value string `json:"value" binding:"required"`
+	}
 )
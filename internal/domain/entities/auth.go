@@ -1,5 +1,7 @@
 package entities
 
+import "time"
+
 type (
 	User struct {
 		UserID   string `json:"user_id"`
@@ -21,6 +23,14 @@ type (
 		AccountType string `json:"account_type,omitempty"`
 	}
 
+	// Identity is the authenticated caller behind a request.
+	Identity struct {
+		UserID      string    `json:"user_id"`
+		AccountType string    `json:"account_type,omitempty"`
+		ExpiresAt   time.Time `json:"expires_at,omitempty"`
+		Token       string    `json:"-"`
+	}
+
 	UserResponse struct {
 		Data User `json:"data"`
 	}
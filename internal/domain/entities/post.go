@@ -1,11 +1,39 @@
 package entities
 
+// InteractionType is the kind of an Interaction. The posts service stores
+// the type as a plain number without defining its values, so they are
+// defined here by the gateway, the only writer of interactions; renumbering
+// them changes the meaning of stored interactions. 0 is left for unknown
+// types.
+type InteractionType int
+
+const (
+	InteractionLike InteractionType = iota + 1
+	InteractionComment
+	InteractionReaction
+)
+
+var interactionTypeNames = map[InteractionType]string{
+	InteractionLike:     "like",
+	InteractionComment:  "comment",
+	InteractionReaction: "reaction",
+}
+
+func (t InteractionType) String() string {
+	if name, ok := interactionTypeNames[t]; ok {
+		return name
+	}
+	return "unknown"
+}
+
 type (
+	// Interaction is a like, a comment (Value is the text) or a reaction
+	// (Value is the reaction) left by UserId on a post.
 	Interaction struct {
-		Type      int    `json:"type"`
-		Value     string `json:"value"`
-		UserId    string `json:"userId"`
-		CreatedAt string `json:"createdAt"`
+		Type      InteractionType `json:"type"`
+		Value     string          `json:"value"`
+		UserId    string          `json:"userId"`
+		CreatedAt string          `json:"createdAt"`
 	}
 
 	Post struct {
@@ -16,5 +44,21 @@ type (
 		Author       string        `json:"author"`
 		CreatedAt    string        `json:"createdAt"`
 		Interactions []Interaction `json:"interactions"`
+		// LikeCount y CommentCount los calcula el gateway a partir de Interactions.
+		LikeCount    int `json:"likeCount"`
+		CommentCount int `json:"commentCount"`
 	}
 )
+
+// Count fills LikeCount and CommentCount from the interactions.
+func (p *Post) Count() {
+	p.LikeCount, p.CommentCount = 0, 0
+	for _, interaction := range p.Interactions {
+		switch interaction.Type {
+		case InteractionLike:
+			p.LikeCount++
+		case InteractionComment:
+			p.CommentCount++
+		}
+	}
+}
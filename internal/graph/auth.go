@@ -0,0 +1,60 @@
+package graph
+
+import (
+	"context"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/identity"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/policy"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/requestid"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+	"github.com/graphql-go/graphql"
+	"net/http"
+)
+
+// authorize aplica rule al usuario que el middleware dejó en el contexto.
+func authorize(params graphql.ResolveParams, rule policy.Rule) utils.ApiError {
+	id, _ := identity.FromContext(params.Context)
+	return policy.Authorize(id, rule, "")
+}
+
+// userToken devuelve el token validado por el middleware a partir de los
+// headers y, solo si no hay, el argumento "token" que queda por compatibilidad.
+func userToken(params graphql.ResolveParams) (string, utils.ApiError) {
+	if id, ok := identity.FromContext(params.Context); ok && id.Token != "" {
+		return id.Token, nil
+	}
+	if token, _ := params.Args["token"].(string); token != "" {
+		return token, nil
+	}
+	return "", utils.NewApiError(policy.ErrUnauthenticated, http.StatusUnauthorized)
+}
+
+// errorResponse arma el sobre {"data", "error"} que usan los resolvers.
+func errorResponse(ctx context.Context, apiErr utils.ApiError) map[string]interface{} {
+	return map[string]interface{}{
+		"data":  nil,
+		"error": utils.ErrorBody(ctx, apiErr),
+	}
+}
+
+// graphError is returned by resolvers whose type has no error field; status
+// and request id travel in the GraphQL error extensions.
+type graphError struct {
+	apiErr    utils.ApiError
+	requestID string
+}
+
+func newGraphError(ctx context.Context, apiErr utils.ApiError) *graphError {
+	return &graphError{apiErr: apiErr, requestID: requestid.FromContext(ctx)}
+}
+
+func (e *graphError) Error() string {
+	return e.apiErr.Message().Error()
+}
+
+func (e *graphError) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"status": e.apiErr.Status()}
+	if e.requestID != "" {
+		ext["request_id"] = e.requestID
+	}
+	return ext
+}
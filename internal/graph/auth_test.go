@@ -0,0 +1,40 @@
+package graph
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/identity"
+	"github.com/graphql-go/graphql"
+)
+
+func TestUserToken(t *testing.T) {
+	fromHeader := identity.NewContext(context.Background(), &entities.Identity{UserID: "u1", Token: "header"})
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		args   map[string]interface{}
+		want   string
+		status int
+	}{
+		{"header only", fromHeader, nil, "header", 0},
+		{"header wins over the argument", fromHeader, map[string]interface{}{"token": "arg"}, "header", 0},
+		{"deprecated argument", context.Background(), map[string]interface{}{"token": "arg"}, "arg", 0},
+		{"no token", context.Background(), nil, "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, apiErr := userToken(graphql.ResolveParams{Context: tt.ctx, Args: tt.args})
+			status := 0
+			if apiErr != nil {
+				status = apiErr.Status()
+			}
+			if token != tt.want || status != tt.status {
+				t.Fatalf("userToken() = %q, %d; want %q, %d", token, status, tt.want, tt.status)
+			}
+		})
+	}
+}
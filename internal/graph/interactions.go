@@ -0,0 +1,69 @@
+package graph
+
+import (
+	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/services/postservice"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+	"github.com/graphql-go/graphql"
+)
+
+// interactionTypeEnum expone entities.InteractionType; los tipos desconocidos salen como null.
+var interactionTypeEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "InteractionType",
+	Values: graphql.EnumValueConfigMap{
+		"LIKE":     &graphql.EnumValueConfig{Value: entities.InteractionLike},
+		"COMMENT":  &graphql.EnumValueConfig{Value: entities.InteractionComment},
+		"REACTION": &graphql.EnumValueConfig{Value: entities.InteractionReaction},
+	},
+})
+
+// interactionFields are the mutations that like, comment on and react to a
+// post. The user always comes from the token.
+func interactionFields(postsService postservice.PostsService, postResponseType *graphql.Object) graphql.Fields {
+	idArg := &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}
+	field := func(args graphql.FieldConfigArgument, apply func(params graphql.ResolveParams, id string) (*entities.Post, utils.ApiError)) *graphql.Field {
+		args["_id"] = idArg
+		return &graphql.Field{
+			Type: postResponseType,
+			Args: args,
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				post, apiErr := apply(params, params.Args["_id"].(string))
+				if apiErr != nil {
+					return errorResponse(params.Context, apiErr), nil
+				}
+				return map[string]interface{}{
+					"data":  post,
+					"error": nil,
+				}, nil
+			},
+		}
+	}
+	str := func(params graphql.ResolveParams, name string) string {
+		value, _ := params.Args[name].(string)
+		return value
+	}
+	required := func(name string) graphql.FieldConfigArgument {
+		return graphql.FieldConfigArgument{name: &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}}
+	}
+
+	return graphql.Fields{
+		"likePost": field(graphql.FieldConfigArgument{}, func(params graphql.ResolveParams, id string) (*entities.Post, utils.ApiError) {
+			return postsService.Like(params.Context, id)
+		}),
+		"unlikePost": field(graphql.FieldConfigArgument{}, func(params graphql.ResolveParams, id string) (*entities.Post, utils.ApiError) {
+			return postsService.Unlike(params.Context, id)
+		}),
+		"commentPost": field(required("value"), func(params graphql.ResolveParams, id string) (*entities.Post, utils.ApiError) {
+			return postsService.Comment(params.Context, id, str(params, "value"))
+		}),
+		"deleteComment": field(required("createdAt"), func(params graphql.ResolveParams, id string) (*entities.Post, utils.ApiError) {
+			return postsService.DeleteComment(params.Context, id, str(params, "createdAt"))
+		}),
+		"reactPost": field(required("reaction"), func(params graphql.ResolveParams, id string) (*entities.Post, utils.ApiError) {
+			return postsService.React(params.Context, id, str(params, "reaction"))
+		}),
+		"unreactPost": field(required("reaction"), func(params graphql.ResolveParams, id string) (*entities.Post, utils.ApiError) {
+			return postsService.Unreact(params.Context, id, str(params, "reaction"))
+		}),
+	}
+}
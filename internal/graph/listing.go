@@ -0,0 +1,57 @@
+package graph
+
+import (
+	"github.com/Cococtel/Cococtel_Gagateway/internal/listing"
+	"github.com/graphql-go/graphql"
+)
+
+var pageInfoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PageInfo",
+	Fields: graphql.Fields{
+		"total":       &graphql.Field{Type: graphql.Int},
+		"limit":       &graphql.Field{Type: graphql.Int},
+		"offset":      &graphql.Field{Type: graphql.Int},
+		"next_cursor": &graphql.Field{Type: graphql.String},
+	},
+})
+
+// listArgs are the pagination and sorting arguments of the listings; the
+// filters are added per field.
+func listArgs(filters graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	args := graphql.FieldConfigArgument{
+		"limit":  &graphql.ArgumentConfig{Type: graphql.Int, Description: "Tamaño de página; sin límite devuelve todo."},
+		"offset": &graphql.ArgumentConfig{Type: graphql.Int},
+		"after":  &graphql.ArgumentConfig{Type: graphql.String, Description: "El next_cursor de la página anterior."},
+		"sort":   &graphql.ArgumentConfig{Type: graphql.String, Description: "name, createdAt, averageRating o likes."},
+		"order":  &graphql.ArgumentConfig{Type: graphql.String, Description: "asc o desc."},
+	}
+	for name, arg := range filters {
+		args[name] = arg
+	}
+	return args
+}
+
+func listQuery(params graphql.ResolveParams) listing.Query {
+	str := func(name string) string {
+		value, _ := params.Args[name].(string)
+		return value
+	}
+	limit, _ := params.Args["limit"].(int)
+	offset, _ := params.Args["offset"].(int)
+	q := listing.Query{
+		Limit:  limit,
+		Offset: offset,
+		Cursor: str("after"),
+		Sort:   str("sort"),
+		Desc:   str("order") == "desc",
+		Filter: listing.Filter{
+			Category:  str("category"),
+			Liquor:    str("liquor"),
+			CreatorID: str("creatorId"),
+		},
+	}
+	if minRating, ok := params.Args["minRating"].(float64); ok {
+		q.Filter.MinRating = &minRating
+	}
+	return q
+}
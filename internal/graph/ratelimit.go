@@ -0,0 +1,22 @@
+package graph
+
+import (
+	"github.com/Cococtel/Cococtel_Gagateway/internal/defines"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/ratelimit"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+	"github.com/graphql-go/graphql"
+)
+
+// takeExpensive cobra el campo al presupuesto caro del usuario, además del
+// barato que el middleware ya cobró por el request de /graphql.
+func takeExpensive(params graphql.ResolveParams, limiter *ratelimit.Limiter) utils.ApiError {
+	key, ok := ratelimit.KeyFromContext(params.Context)
+	if !ok {
+		return nil
+	}
+	result, applied := limiter.Take(params.Context, ratelimit.Expensive, key)
+	if applied && !result.Allowed {
+		return utils.NewTooManyRequestsError(defines.RateLimited, result.RetryAfter)
+	}
+	return nil
+}
@@ -2,10 +2,17 @@ package graph
 
 import (
 	"encoding/base64"
+	"errors"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/config"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/services/postservice"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/services/searchservice"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+	"net/http"
 	"strings"
 
 	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/dtos"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/policy"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/ratelimit"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/services/authservice"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/services/catalogservice"
 	"github.com/graphql-go/graphql"
@@ -17,6 +24,9 @@ func NewSchema(
 	scrappingService catalogservice.IScrapping,
 	aiService catalogservice.IAI,
 	postsService postservice.PostsService,
+	searchService searchservice.ISearch,
+	limiter *ratelimit.Limiter,
+	features config.Features,
 ) graphql.SchemaConfig {
 	// Tipos ya definidos
 	liquorType := graphql.NewObject(graphql.ObjectConfig{
@@ -111,8 +121,9 @@ func NewSchema(
 	errorType := graphql.NewObject(graphql.ObjectConfig{
 		Name: "Error",
 		Fields: graphql.Fields{
-			"message": &graphql.Field{Type: graphql.String},
-			"status":  &graphql.Field{Type: graphql.Int},
+			"message":    &graphql.Field{Type: graphql.String},
+			"status":     &graphql.Field{Type: graphql.Int},
+			"request_id": &graphql.Field{Type: graphql.String},
 		},
 	})
 
@@ -120,7 +131,7 @@ func NewSchema(
 	interactionType := graphql.NewObject(graphql.ObjectConfig{
 		Name: "Interaction",
 		Fields: graphql.Fields{
-			"type":      &graphql.Field{Type: graphql.Int},
+			"type":      &graphql.Field{Type: interactionTypeEnum},
 			"value":     &graphql.Field{Type: graphql.String},
 			"userId":    &graphql.Field{Type: graphql.String},
 			"createdAt": &graphql.Field{Type: graphql.String},
@@ -136,6 +147,8 @@ func NewSchema(
 			"author":       &graphql.Field{Type: graphql.String},
 			"createdAt":    &graphql.Field{Type: graphql.String},
 			"interactions": &graphql.Field{Type: graphql.NewList(interactionType)},
+			"likeCount":    &graphql.Field{Type: graphql.Int},
+			"commentCount": &graphql.Field{Type: graphql.Int},
 		},
 	})
 
@@ -152,6 +165,7 @@ func NewSchema(
 		Fields: graphql.Fields{
 			"data":  &graphql.Field{Type: graphql.NewList(liquorType)},
 			"error": &graphql.Field{Type: errorType},
+			"meta":  &graphql.Field{Type: pageInfoType},
 		},
 	})
 	recipeResponseType := graphql.NewObject(graphql.ObjectConfig{
@@ -166,6 +180,7 @@ func NewSchema(
 		Fields: graphql.Fields{
 			"data":  &graphql.Field{Type: graphql.NewList(recipeType)},
 			"error": &graphql.Field{Type: errorType},
+			"meta":  &graphql.Field{Type: pageInfoType},
 		},
 	})
 	userResponseType := graphql.NewObject(graphql.ObjectConfig{
@@ -253,24 +268,27 @@ func NewSchema(
 		},
 	})
 
+	// Los clientes deben mandar el token en x-auth-token o Authorization: Bearer.
+	deprecatedTokenArg := &graphql.ArgumentConfig{
+		Type:        graphql.String,
+		Description: "Deprecated: send the user token in the x-auth-token or Authorization: Bearer header instead. Ignored when a valid header token is present.",
+	}
+
 	getUserField := &graphql.Field{
 		Type: userResponseType,
 		Args: graphql.FieldConfigArgument{
 			"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
-			"token": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			"token": deprecatedTokenArg,
 		},
 		Resolve: func(params graphql.ResolveParams) (interface{}, error) {
 			id := params.Args["id"].(string)
-			token := params.Args["token"].(string)
-			user, apiErr := authService.GetUser(id, token)
+			token, apiErr := userToken(params)
 			if apiErr != nil {
-				return map[string]interface{}{
-					"data": nil,
-					"error": map[string]interface{}{
-						"message": apiErr.Message().Error(),
-						"status":  apiErr.Status(),
-					},
-				}, nil
+				return errorResponse(params.Context, apiErr), nil
+			}
+			user, apiErr := authService.GetUser(params.Context, id, token)
+			if apiErr != nil {
+				return errorResponse(params.Context, apiErr), nil
 			}
 			return map[string]interface{}{
 				"data":  user,
@@ -283,10 +301,13 @@ func NewSchema(
 		Type: editProfileResponseType,
 		Args: graphql.FieldConfigArgument{
 			"user":  &graphql.ArgumentConfig{Type: userInputType},
-			"token": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			"token": deprecatedTokenArg,
 		},
 		Resolve: func(params graphql.ResolveParams) (interface{}, error) {
-			token := params.Args["token"].(string)
+			token, apiErr := userToken(params)
+			if apiErr != nil {
+				return errorResponse(params.Context, apiErr), nil
+			}
 			var userInput dtos.User
 			if rawUser, ok := params.Args["user"].(map[string]interface{}); ok {
 				if val, exists := rawUser["name"]; exists {
@@ -314,15 +335,9 @@ func NewSchema(
 					userInput.Username = &strVal
 				}
 			}
-			apiErr := authService.EditProfile(userInput, token)
+			apiErr = authService.EditProfile(params.Context, userInput, token)
 			if apiErr != nil {
-				return map[string]interface{}{
-					"data": nil,
-					"error": map[string]interface{}{
-						"message": apiErr.Message().Error(),
-						"status":  apiErr.Status(),
-					},
-				}, nil
+				return errorResponse(params.Context, apiErr), nil
 			}
 			return map[string]interface{}{
 				"data":  "Profile updated successfully",
@@ -335,20 +350,18 @@ func NewSchema(
 	queryFields := graphql.Fields{
 		"liquors": &graphql.Field{
 			Type: liquorsResponseType,
+			Args: listArgs(graphql.FieldConfigArgument{
+				"category": &graphql.ArgumentConfig{Type: graphql.String},
+			}),
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
-				liquors, apiErr := catalogService.GetLiquors()
+				liquors, page, apiErr := catalogService.ListLiquors(params.Context, listQuery(params))
 				if apiErr != nil {
-					return map[string]interface{}{
-						"data": nil,
-						"error": map[string]interface{}{
-							"message": apiErr.Message().Error(),
-							"status":  apiErr.Status(),
-						},
-					}, nil
+					return errorResponse(params.Context, apiErr), nil
 				}
 				return map[string]interface{}{
 					"data":  liquors,
 					"error": nil,
+					"meta":  page,
 				}, nil
 			},
 		},
@@ -359,15 +372,9 @@ func NewSchema(
 			},
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
 				id := params.Args["_id"].(string)
-				liquor, apiErr := catalogService.GetLiquorByID(id)
+				liquor, apiErr := catalogService.GetLiquorByID(params.Context, id)
 				if apiErr != nil {
-					return map[string]interface{}{
-						"data": nil,
-						"error": map[string]interface{}{
-							"message": apiErr.Message().Error(),
-							"status":  apiErr.Status(),
-						},
-					}, nil
+					return errorResponse(params.Context, apiErr), nil
 				}
 				return map[string]interface{}{
 					"data":  liquor,
@@ -377,12 +384,18 @@ func NewSchema(
 		},
 		"recipes": &graphql.Field{
 			Type: recipesResponseType,
+			Args: listArgs(graphql.FieldConfigArgument{
+				"category":  &graphql.ArgumentConfig{Type: graphql.String},
+				"liquor":    &graphql.ArgumentConfig{Type: graphql.String},
+				"creatorId": &graphql.ArgumentConfig{Type: graphql.String},
+				"minRating": &graphql.ArgumentConfig{Type: graphql.Float},
+			}),
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
-				recipes, apiErr := catalogService.GetRecipes()
+				recipes, page, apiErr := catalogService.ListRecipes(params.Context, listQuery(params))
 				if apiErr != nil {
-					return map[string]interface{}{"data": nil, "error": apiErr}, nil
+					return errorResponse(params.Context, apiErr), nil
 				}
-				return map[string]interface{}{"data": recipes, "error": nil}, nil
+				return map[string]interface{}{"data": recipes, "error": nil, "meta": page}, nil
 			},
 		},
 		"recipe": &graphql.Field{
@@ -392,9 +405,9 @@ func NewSchema(
 			},
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
 				id := params.Args["_id"].(string)
-				recipe, apiErr := catalogService.GetRecipeByID(id)
+				recipe, apiErr := catalogService.GetRecipeByID(params.Context, id)
 				if apiErr != nil {
-					return map[string]interface{}{"data": nil, "error": apiErr}, nil
+					return errorResponse(params.Context, apiErr), nil
 				}
 				return map[string]interface{}{"data": recipe, "error": nil}, nil
 			},
@@ -402,15 +415,9 @@ func NewSchema(
 		"posts": &graphql.Field{
 			Type: postsResponseType,
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
-				posts, apiErr := postsService.GetPosts()
+				posts, apiErr := postsService.GetPosts(params.Context)
 				if apiErr != nil {
-					return map[string]interface{}{
-						"data": nil,
-						"error": map[string]interface{}{
-							"message": apiErr.Message().Error(),
-							"status":  apiErr.Status(),
-						},
-					}, nil
+					return errorResponse(params.Context, apiErr), nil
 				}
 				return map[string]interface{}{
 					"data":  posts,
@@ -425,15 +432,9 @@ func NewSchema(
 			},
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
 				id := params.Args["_id"].(string)
-				post, apiErr := postsService.GetPostByID(id)
+				post, apiErr := postsService.GetPostByID(params.Context, id)
 				if apiErr != nil {
-					return map[string]interface{}{
-						"data": nil,
-						"error": map[string]interface{}{
-							"message": apiErr.Message().Error(),
-							"status":  apiErr.Status(),
-						},
-					}, nil
+					return errorResponse(params.Context, apiErr), nil
 				}
 				return map[string]interface{}{
 					"data":  post,
@@ -444,19 +445,16 @@ func NewSchema(
 		"verify": &graphql.Field{
 			Type: verifyResponseType,
 			Args: graphql.FieldConfigArgument{
-				"token": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"token": deprecatedTokenArg,
 			},
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
-				token := params.Args["token"].(string)
-				apiErr := authService.Verify(token)
+				token, apiErr := userToken(params)
+				if apiErr != nil {
+					return errorResponse(params.Context, apiErr), nil
+				}
+				apiErr = authService.Verify(params.Context, token)
 				if apiErr != nil {
-					return map[string]interface{}{
-						"data": nil,
-						"error": map[string]interface{}{
-							"message": apiErr.Message().Error(),
-							"status":  apiErr.Status(),
-						},
-					}, nil
+					return errorResponse(params.Context, apiErr), nil
 				}
 				return map[string]interface{}{
 					"data":  "ok",
@@ -471,16 +469,13 @@ func NewSchema(
 				"code": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
 			},
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				if apiErr := takeExpensive(params, limiter); apiErr != nil {
+					return errorResponse(params.Context, apiErr), nil
+				}
 				code := params.Args["code"].(string)
-				product, apiErr := scrappingService.GetProductByCode(code)
+				product, apiErr := scrappingService.GetProductByCode(params.Context, code)
 				if apiErr != nil {
-					return map[string]interface{}{
-						"data": nil,
-						"error": map[string]interface{}{
-							"message": apiErr.Message().Error(),
-							"status":  apiErr.Status(),
-						},
-					}, nil
+					return errorResponse(params.Context, apiErr), nil
 				}
 				return map[string]interface{}{
 					"data":  product,
@@ -501,6 +496,9 @@ func NewSchema(
 				"additional_attributes": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
 			},
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				if apiErr := authorize(params, policy.LiquorWrite); apiErr != nil {
+					return errorResponse(params.Context, apiErr), nil
+				}
 				liquor := dtos.Liquor{
 					Name:                 params.Args["name"].(string),
 					EAN:                  params.Args["EAN"].(int),
@@ -508,15 +506,9 @@ func NewSchema(
 					Description:          params.Args["description"].(string),
 					AdditionalAttributes: params.Args["additional_attributes"].(string),
 				}
-				newLiquor, apiErr := catalogService.CreateLiquor(liquor)
+				newLiquor, apiErr := catalogService.CreateLiquor(params.Context, liquor)
 				if apiErr != nil {
-					return map[string]interface{}{
-						"data": nil,
-						"error": map[string]interface{}{
-							"message": apiErr.Message().Error(),
-							"status":  apiErr.Status(),
-						},
-					}, nil
+					return errorResponse(params.Context, apiErr), nil
 				}
 				return map[string]interface{}{
 					"data":  newLiquor,
@@ -535,6 +527,9 @@ func NewSchema(
 				"additional_attributes": &graphql.ArgumentConfig{Type: graphql.String},
 			},
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				if apiErr := authorize(params, policy.LiquorWrite); apiErr != nil {
+					return errorResponse(params.Context, apiErr), nil
+				}
 				id := params.Args["_id"].(string)
 				updates := make(map[string]interface{})
 				for key, value := range params.Args {
@@ -542,15 +537,9 @@ func NewSchema(
 						updates[key] = value
 					}
 				}
-				updatedLiquor, apiErr := catalogService.UpdateLiquor(id, updates)
+				updatedLiquor, apiErr := catalogService.UpdateLiquor(params.Context, id, updates)
 				if apiErr != nil {
-					return map[string]interface{}{
-						"data": nil,
-						"error": map[string]interface{}{
-							"message": apiErr.Message().Error(),
-							"status":  apiErr.Status(),
-						},
-					}, nil
+					return errorResponse(params.Context, apiErr), nil
 				}
 				return map[string]interface{}{
 					"data":  updatedLiquor,
@@ -570,16 +559,13 @@ func NewSchema(
 				"_id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
 			},
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				if apiErr := authorize(params, policy.LiquorWrite); apiErr != nil {
+					return errorResponse(params.Context, apiErr), nil
+				}
 				id := params.Args["_id"].(string)
-				apiErr := catalogService.DeleteLiquor(id)
+				apiErr := catalogService.DeleteLiquor(params.Context, id)
 				if apiErr != nil {
-					return map[string]interface{}{
-						"data": nil,
-						"error": map[string]interface{}{
-							"message": apiErr.Message().Error(),
-							"status":  apiErr.Status(),
-						},
-					}, nil
+					return errorResponse(params.Context, apiErr), nil
 				}
 				return map[string]interface{}{
 					"data":  "liquor deleted successfully",
@@ -600,8 +586,11 @@ func NewSchema(
 					},
 				}))},
 				"instructions": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.String)},
-				"creatorId":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
-				"description":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"creatorId": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Ignorado: el creador se toma del token.",
+				},
+				"description": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
 			},
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
 				ingredientsInterface, _ := params.Args["ingredients"].([]interface{})
@@ -625,12 +614,11 @@ func NewSchema(
 					Category:     params.Args["category"].(string),
 					Ingredients:  ingredients,
 					Instructions: instructions,
-					CreatorId:    params.Args["creatorId"].(string),
 					Description:  params.Args["description"].(string),
 				}
-				newRecipe, apiErr := catalogService.CreateRecipe(recipe)
+				newRecipe, apiErr := catalogService.CreateRecipe(params.Context, recipe)
 				if apiErr != nil {
-					return nil, apiErr.Message()
+					return nil, newGraphError(params.Context, apiErr)
 				}
 				return newRecipe, nil
 			},
@@ -651,9 +639,9 @@ func NewSchema(
 						updates[key] = value
 					}
 				}
-				updatedRecipe, apiErr := catalogService.UpdateRecipe(id, updates)
+				updatedRecipe, apiErr := catalogService.UpdateRecipe(params.Context, id, updates)
 				if apiErr != nil {
-					return nil, apiErr.Message()
+					return nil, newGraphError(params.Context, apiErr)
 				}
 				return updatedRecipe, nil
 			},
@@ -665,9 +653,9 @@ func NewSchema(
 			},
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
 				id := params.Args["_id"].(string)
-				err := catalogService.DeleteRecipe(id)
+				err := catalogService.DeleteRecipe(params.Context, id)
 				if err != nil {
-					return false, err.Message()
+					return false, newGraphError(params.Context, err)
 				}
 				return true, nil
 			},
@@ -678,24 +666,21 @@ func NewSchema(
 				"urlImage": &graphql.ArgumentConfig{Type: graphql.String},
 				"title":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
 				"content":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
-				"author":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"author": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Ignorado: el autor se toma del token.",
+				},
 			},
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				urlImage, _ := params.Args["urlImage"].(string)
 				post := dtos.Post{
-					UrlImage: params.Args["urlImage"].(string),
+					UrlImage: urlImage,
 					Title:    params.Args["title"].(string),
 					Content:  params.Args["content"].(string),
-					Author:   params.Args["author"].(string),
 				}
-				newPost, apiErr := postsService.CreatePost(post)
+				newPost, apiErr := postsService.CreatePost(params.Context, post)
 				if apiErr != nil {
-					return map[string]interface{}{
-						"data": nil,
-						"error": map[string]interface{}{
-							"message": apiErr.Message().Error(),
-							"status":  apiErr.Status(),
-						},
-					}, nil
+					return errorResponse(params.Context, apiErr), nil
 				}
 				return map[string]interface{}{
 					"data":  newPost,
@@ -720,15 +705,9 @@ func NewSchema(
 						updates[key] = value
 					}
 				}
-				updatedPost, apiErr := postsService.UpdatePost(id, updates)
+				updatedPost, apiErr := postsService.UpdatePost(params.Context, id, updates)
 				if apiErr != nil {
-					return map[string]interface{}{
-						"data": nil,
-						"error": map[string]interface{}{
-							"message": apiErr.Message().Error(),
-							"status":  apiErr.Status(),
-						},
-					}, nil
+					return errorResponse(params.Context, apiErr), nil
 				}
 				return map[string]interface{}{
 					"data":  updatedPost,
@@ -749,15 +728,9 @@ func NewSchema(
 			},
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
 				id := params.Args["_id"].(string)
-				apiErr := postsService.DeletePost(id)
+				apiErr := postsService.DeletePost(params.Context, id)
 				if apiErr != nil {
-					return map[string]interface{}{
-						"data": nil,
-						"error": map[string]interface{}{
-							"message": apiErr.Message().Error(),
-							"status":  apiErr.Status(),
-						},
-					}, nil
+					return errorResponse(params.Context, apiErr), nil
 				}
 				return map[string]interface{}{
 					"data":  "post deleted successfully",
@@ -788,9 +761,9 @@ func NewSchema(
 					Password: stringToPointer(params.Args["password"].(string)),
 					Type:     stringToPointer(params.Args["type"].(string)),
 				}
-				newUser, apiErr := authService.Register(user)
+				newUser, apiErr := authService.Register(params.Context, user)
 				if apiErr != nil {
-					return map[string]interface{}{"data": nil, "error": apiErr}, nil
+					return errorResponse(params.Context, apiErr), nil
 				}
 				return map[string]interface{}{"data": newUser, "error": nil}, nil
 			},
@@ -814,15 +787,9 @@ func NewSchema(
 					Password: password,
 					Type:     accountType,
 				}
-				loginResponse, apiErr := authService.Login(credentials)
+				loginResponse, apiErr := authService.Login(params.Context, credentials)
 				if apiErr != nil {
-					return map[string]interface{}{
-						"data": nil,
-						"error": map[string]interface{}{
-							"message": apiErr.Message().Error(),
-							"status":  apiErr.Status(),
-						},
-					}, nil
+					return errorResponse(params.Context, apiErr), nil
 				}
 				return map[string]interface{}{
 					"data":  loginResponse,
@@ -836,15 +803,12 @@ func NewSchema(
 				"input": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.String)},
 			},
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				if apiErr := takeExpensive(params, limiter); apiErr != nil {
+					return errorResponse(params.Context, apiErr), nil
+				}
 				rawInput, ok := params.Args["input"].([]interface{})
 				if !ok {
-					return map[string]interface{}{
-						"data": nil,
-						"error": map[string]interface{}{
-							"message": "Invalid input format",
-							"status":  400,
-						},
-					}, nil
+					return errorResponse(params.Context, utils.NewApiError(errors.New("Invalid input format"), http.StatusBadRequest)), nil
 				}
 				var input []string
 				for _, item := range rawInput {
@@ -852,15 +816,9 @@ func NewSchema(
 						input = append(input, str)
 					}
 				}
-				result, apiErr := aiService.ProcessStrings(input)
+				result, apiErr := aiService.ProcessStrings(params.Context, input)
 				if apiErr != nil {
-					return map[string]interface{}{
-						"data": nil,
-						"error": map[string]interface{}{
-							"message": apiErr.Message().Error(),
-							"status":  apiErr.Status(),
-						},
-					}, nil
+					return errorResponse(params.Context, apiErr), nil
 				}
 				return map[string]interface{}{
 					"data":  result,
@@ -874,16 +832,13 @@ func NewSchema(
 				"liquor": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
 			},
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				if apiErr := takeExpensive(params, limiter); apiErr != nil {
+					return errorResponse(params.Context, apiErr), nil
+				}
 				liquor := params.Args["liquor"].(string)
-				recipe, apiErr := aiService.CreateRecipe(liquor)
+				recipe, apiErr := aiService.CreateRecipe(params.Context, liquor)
 				if apiErr != nil {
-					return map[string]interface{}{
-						"data": nil,
-						"error": map[string]interface{}{
-							"message": apiErr.Message().Error(),
-							"status":  apiErr.Status(),
-						},
-					}, nil
+					return errorResponse(params.Context, apiErr), nil
 				}
 				return map[string]interface{}{
 					"data":  recipe,
@@ -897,26 +852,17 @@ func NewSchema(
 				"imageBase64": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
 			},
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				if apiErr := takeExpensive(params, limiter); apiErr != nil {
+					return errorResponse(params.Context, apiErr), nil
+				}
 				imageBase64 := params.Args["imageBase64"].(string)
 				imageBytes, err := decodeBase64(imageBase64)
 				if err != nil {
-					return map[string]interface{}{
-						"data": nil,
-						"error": map[string]interface{}{
-							"message": "Invalid base64 string",
-							"status":  400,
-						},
-					}, nil
-				}
-				texts, apiErr := aiService.ExtractTextFromImage(imageBytes)
+					return errorResponse(params.Context, utils.NewApiError(errors.New("Invalid base64 string"), http.StatusBadRequest)), nil
+				}
+				texts, apiErr := aiService.ExtractTextFromImage(params.Context, imageBytes)
 				if apiErr != nil {
-					return map[string]interface{}{
-						"data": nil,
-						"error": map[string]interface{}{
-							"message": apiErr.Message().Error(),
-							"status":  apiErr.Status(),
-						},
-					}, nil
+					return errorResponse(params.Context, apiErr), nil
 				}
 				return map[string]interface{}{
 					"data":  texts,
@@ -927,14 +873,32 @@ func NewSchema(
 		"editProfile": editProfileField,
 	}
 
+	// Como en REST, los servicios apagados no tienen campos.
+	if !features.AI {
+		delete(mutationFields, "processStrings")
+		delete(mutationFields, "createAIRecipe")
+		delete(mutationFields, "extractTextFromImageBytes")
+	}
+	if !features.Scrapping {
+		delete(queryFields, "getProductByCode")
+	}
+	// Sin FEATURE_SEARCH no hay índice ni campo search.
+	if searchService != nil {
+		queryFields["search"] = searchField(searchService, liquorType, recipeType, postType, errorType)
+	}
+
 	queryType := graphql.NewObject(graphql.ObjectConfig{
 		Name:   "Query",
-		Fields: queryFields,
+		Fields: traceFields("Query", requireScopes(queryFields)),
 	})
 
+	for name, field := range interactionFields(postsService, postResponseType) {
+		mutationFields[name] = field
+	}
+
 	mutationType := graphql.NewObject(graphql.ObjectConfig{
 		Name:   "Mutation",
-		Fields: mutationFields,
+		Fields: traceFields("Mutation", requireScopes(mutationFields)),
 	})
 
 	return graphql.SchemaConfig{Query: queryType, Mutation: mutationType}
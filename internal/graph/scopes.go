@@ -0,0 +1,88 @@
+package graph
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/apikeys"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/defines"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+	"github.com/graphql-go/graphql"
+)
+
+// fieldScopes is the API key scope each root field needs besides graphql:
+// the same one its REST route asks for.
+var fieldScopes = map[string]apikeys.Scope{
+	"liquors": apikeys.ScopeCatalogRead,
+	"liquor":  apikeys.ScopeCatalogRead,
+	"recipes": apikeys.ScopeCatalogRead,
+	"recipe":  apikeys.ScopeCatalogRead,
+	"posts":   apikeys.ScopeCatalogRead,
+	"post":    apikeys.ScopeCatalogRead,
+	"search":  apikeys.ScopeCatalogRead,
+
+	"createLiquor":  apikeys.ScopeCatalogWrite,
+	"updateLiquor":  apikeys.ScopeCatalogWrite,
+	"deleteLiquor":  apikeys.ScopeCatalogWrite,
+	"createRecipe":  apikeys.ScopeCatalogWrite,
+	"updateRecipe":  apikeys.ScopeCatalogWrite,
+	"deleteRecipe":  apikeys.ScopeCatalogWrite,
+	"createPost":    apikeys.ScopeCatalogWrite,
+	"updatePost":    apikeys.ScopeCatalogWrite,
+	"deletePost":    apikeys.ScopeCatalogWrite,
+	"likePost":      apikeys.ScopeCatalogWrite,
+	"unlikePost":    apikeys.ScopeCatalogWrite,
+	"commentPost":   apikeys.ScopeCatalogWrite,
+	"deleteComment": apikeys.ScopeCatalogWrite,
+	"reactPost":     apikeys.ScopeCatalogWrite,
+	"unreactPost":   apikeys.ScopeCatalogWrite,
+
+	"verify":      apikeys.ScopeAuth,
+	"getUser":     apikeys.ScopeAuth,
+	"register":    apikeys.ScopeAuth,
+	"login":       apikeys.ScopeAuth,
+	"editProfile": apikeys.ScopeAuth,
+
+	// El scraping usa el mismo scope que en REST.
+	"getProductByCode":          apikeys.ScopeAI,
+	"processStrings":            apikeys.ScopeAI,
+	"createAIRecipe":            apikeys.ScopeAI,
+	"extractTextFromImageBytes": apikeys.ScopeAI,
+}
+
+// requireScopes wraps the root fields so that an API key without the scope
+// of a field gets a 403 for that field. A field missing from fieldScopes is
+// a programming error.
+func requireScopes(fields graphql.Fields) graphql.Fields {
+	for name, field := range fields {
+		scope, ok := fieldScopes[name]
+		if !ok {
+			panic(fmt.Sprintf("graphql field %q has no API key scope", name))
+		}
+		field.Resolve = scopeResolve(scope, hasErrorField(field.Type), field.Resolve)
+	}
+	return fields
+}
+
+func scopeResolve(scope apikeys.Scope, envelope bool, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		if key, ok := apikeys.FromContext(params.Context); !ok || !key.Allows(scope) {
+			apiErr := utils.NewApiError(defines.ApiKeyScope, http.StatusForbidden)
+			if envelope {
+				return errorResponse(params.Context, apiErr), nil
+			}
+			return nil, newGraphError(params.Context, apiErr)
+		}
+		return resolve(params)
+	}
+}
+
+// hasErrorField reports whether t is one of the {"data", "error"} envelopes.
+func hasErrorField(t graphql.Output) bool {
+	object, ok := t.(*graphql.Object)
+	if !ok {
+		return false
+	}
+	_, ok = object.Fields()["error"]
+	return ok
+}
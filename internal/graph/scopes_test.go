@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/apikeys"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/config"
+	"github.com/graphql-go/graphql"
+)
+
+func TestEveryRootFieldHasAScope(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatal(r)
+		}
+	}()
+	NewSchema(nil, nil, nil, nil, nil, nil, nil, config.Features{AI: true, Scrapping: true, Search: true})
+}
+
+func TestScopeResolve(t *testing.T) {
+	envelope := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Envelope",
+		Fields: graphql.Fields{
+			"data":  &graphql.Field{Type: graphql.String},
+			"error": &graphql.Field{Type: graphql.NewObject(graphql.ObjectConfig{Name: "Err", Fields: graphql.Fields{"status": &graphql.Field{Type: graphql.Int}}})},
+		},
+	})
+	ok := func(graphql.ResolveParams) (interface{}, error) {
+		return map[string]interface{}{"data": "ok", "error": nil}, nil
+	}
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: requireScopes(graphql.Fields{
+			"liquors": &graphql.Field{Type: graphql.String, Resolve: func(graphql.ResolveParams) (interface{}, error) { return "ok", nil }},
+		})}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{Name: "Mutation", Fields: requireScopes(graphql.Fields{
+			"createLiquor": &graphql.Field{Type: envelope, Resolve: ok},
+		})}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readOnly := &apikeys.Key{Name: "ro", Scopes: []apikeys.Scope{apikeys.ScopeCatalogRead, apikeys.ScopeGraphQL}}
+	tests := []struct {
+		name       string
+		key        *apikeys.Key
+		query      string
+		wantData   string
+		wantStatus int
+	}{
+		{"read with catalog:read", readOnly, `{ liquors }`, `{"liquors":"ok"}`, 0},
+		{"read without key", nil, `{ liquors }`, `{"liquors":null}`, 403},
+		{"write with catalog:read only", readOnly, `mutation { createLiquor { data error { status } } }`, `{"createLiquor":{"data":null,"error":{"status":403}}}`, 0},
+		{"write with catalog:write", &apikeys.Key{Scopes: []apikeys.Scope{apikeys.ScopeCatalogWrite}}, `mutation { createLiquor { data error { status } } }`, `{"createLiquor":{"data":"ok","error":null}}`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.key != nil {
+				ctx = apikeys.NewContext(ctx, tt.key)
+			}
+			result := graphql.Do(graphql.Params{Schema: schema, RequestString: tt.query, Context: ctx})
+			if got := marshal(t, result.Data); got != tt.wantData {
+				t.Fatalf("data = %s, want %s", got, tt.wantData)
+			}
+			status := 0
+			if len(result.Errors) > 0 {
+				status, _ = result.Errors[0].Extensions["status"].(int)
+			}
+			if status != tt.wantStatus {
+				t.Fatalf("error status = %d, want %d (%v)", status, tt.wantStatus, result.Errors)
+			}
+		})
+	}
+}
+
+func marshal(t *testing.T, v interface{}) string {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(raw)
+}
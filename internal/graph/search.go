@@ -0,0 +1,67 @@
+package graph
+
+import (
+	"github.com/Cococtel/Cococtel_Gagateway/internal/search"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/services/searchservice"
+	"github.com/graphql-go/graphql"
+)
+
+// searchField is the search query. Each result fills the field of its type
+// (liquor, recipe or post) and leaves the other two null.
+func searchField(searchService searchservice.ISearch, liquorType, recipeType, postType, errorType *graphql.Object) *graphql.Field {
+	item := func(kind string) graphql.FieldResolveFn {
+		return func(params graphql.ResolveParams) (interface{}, error) {
+			result, ok := params.Source.(search.Result)
+			if !ok || result.Kind != kind {
+				return nil, nil
+			}
+			return result.Item, nil
+		}
+	}
+	searchResultType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "SearchResult",
+		Fields: graphql.Fields{
+			"type":   &graphql.Field{Type: graphql.String},
+			"id":     &graphql.Field{Type: graphql.String},
+			"title":  &graphql.Field{Type: graphql.String},
+			"score":  &graphql.Field{Type: graphql.Float},
+			"liquor": &graphql.Field{Type: liquorType, Resolve: item(search.KindLiquor)},
+			"recipe": &graphql.Field{Type: recipeType, Resolve: item(search.KindRecipe)},
+			"post":   &graphql.Field{Type: postType, Resolve: item(search.KindPost)},
+		},
+	})
+	searchResponseType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "SearchResponse",
+		Fields: graphql.Fields{
+			"data":  &graphql.Field{Type: graphql.NewList(searchResultType)},
+			"error": &graphql.Field{Type: errorType},
+		},
+	})
+
+	return &graphql.Field{
+		Type: searchResponseType,
+		Args: graphql.FieldConfigArgument{
+			"q":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			"types": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.String), Description: "liquor, recipe o post; sin types se busca en todos."},
+			"limit": &graphql.ArgumentConfig{Type: graphql.Int},
+		},
+		Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+			var kinds []string
+			rawKinds, _ := params.Args["types"].([]interface{})
+			for _, kind := range rawKinds {
+				if kind, ok := kind.(string); ok {
+					kinds = append(kinds, kind)
+				}
+			}
+			limit, _ := params.Args["limit"].(int)
+			results, apiErr := searchService.Search(params.Context, params.Args["q"].(string), kinds, limit)
+			if apiErr != nil {
+				return errorResponse(params.Context, apiErr), nil
+			}
+			return map[string]interface{}{
+				"data":  results,
+				"error": nil,
+			}, nil
+		},
+	}
+}
@@ -0,0 +1,52 @@
+package graph
+
+import (
+	"github.com/Cococtel/Cococtel_Gagateway/internal/tracing"
+	"github.com/graphql-go/graphql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceFields envuelve cada resolver de fields en un span hijo del request,
+// así se ve cuánto tarda cada campo de una query que llama a varios servicios.
+func traceFields(typeName string, fields graphql.Fields) graphql.Fields {
+	for name, field := range fields {
+		if field.Resolve != nil {
+			field.Resolve = traceResolve(typeName, name, field.Resolve)
+		}
+	}
+	return fields
+}
+
+func traceResolve(typeName, fieldName string, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	spanName := "graphql." + typeName + "." + fieldName
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		ctx, span := tracing.Tracer().Start(params.Context, spanName,
+			trace.WithAttributes(
+				attribute.String("graphql.field.name", fieldName),
+				attribute.String("graphql.field.parent", typeName),
+			),
+		)
+		defer span.End()
+
+		params.Context = ctx
+		result, err := resolve(params)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return result, err
+		}
+		// Los resolvers con sobre devuelven el error dentro de la respuesta.
+		if envelope, ok := result.(map[string]interface{}); ok {
+			if body, ok := envelope["error"].(map[string]interface{}); ok {
+				message, _ := body["message"].(string)
+				span.SetStatus(codes.Error, message)
+				if status, ok := body["status"].(int); ok {
+					span.SetAttributes(attribute.Int("gateway.error.status", status))
+				}
+			}
+		}
+		return result, nil
+	}
+}
@@ -0,0 +1,166 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/upstream"
+)
+
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusDraining = "draining"
+)
+
+type (
+	// Check is one dependency probed by /readyz. Only required checks make
+	// the gateway unready when they fail.
+	Check struct {
+		Name     string
+		Required bool
+		Probe    func(ctx context.Context) error
+	}
+
+	// Result is what /readyz publishes for a dependency. Error and LastError
+	// only name the kind of failure: probe errors carry hostnames and
+	// addresses, so the full error only goes to the logs.
+	Result struct {
+		Name        string     `json:"name"`
+		Status      string     `json:"status"`
+		Required    bool       `json:"required"`
+		LatencyMs   float64    `json:"latency_ms"`
+		Error       string     `json:"error,omitempty"`
+		LastError   string     `json:"last_error,omitempty"`
+		LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+	}
+
+	Report struct {
+		Status       string   `json:"status"`
+		Dependencies []Result `json:"dependencies"`
+	}
+
+	// Checker runs every check concurrently and keeps the report for ttl, so
+	// /readyz hits do not multiply the traffic sent to the upstreams.
+	Checker struct {
+		checks   []Check
+		timeout  time.Duration
+		ttl      time.Duration
+		draining atomic.Bool
+
+		mu        sync.Mutex
+		report    Report
+		ready     bool
+		probeAt   time.Time
+		lastError map[string]lastError
+	}
+
+	lastError struct {
+		kind string
+		at   time.Time
+	}
+)
+
+func NewChecker(timeout, ttl time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout, ttl: ttl, lastError: make(map[string]lastError)}
+}
+
+// Drain makes every following Run report not ready, so the load balancer
+// stops sending traffic before the server shuts down.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Run reports whether all required dependencies are up, probing them again
+// only when the last report is older than ttl. Concurrent callers wait for
+// the same probe instead of starting their own.
+func (c *Checker) Run(ctx context.Context) (Report, bool) {
+	if c.draining.Load() {
+		return Report{Status: StatusDraining, Dependencies: []Result{}}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.probeAt.IsZero() && time.Since(c.probeAt) < c.ttl {
+		return c.report, c.ready
+	}
+
+	// The probe is shared, so it must not die with the caller that started it.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	defer cancel()
+	c.report, c.ready = c.probe(ctx)
+	c.probeAt = time.Now()
+	return c.report, c.ready
+}
+
+func (c *Checker) probe(ctx context.Context) (Report, bool) {
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Dependencies: results}
+	ready := true
+	for i, result := range results {
+		// Recuerda el último fallo de cada dependencia, aunque ya esté arriba.
+		if result.Error != "" {
+			c.lastError[result.Name] = lastError{kind: result.Error, at: time.Now()}
+		}
+		if last, ok := c.lastError[result.Name]; ok {
+			at := last.at
+			results[i].LastError, results[i].LastErrorAt = last.kind, &at
+		}
+		if result.Required && result.Status != StatusUp {
+			ready = false
+			report.Status = StatusDown
+		}
+	}
+	return report, ready
+}
+
+func run(ctx context.Context, check Check) Result {
+	start := time.Now()
+	err := check.Probe(ctx)
+	latency := time.Since(start)
+	result := Result{
+		Name:      check.Name,
+		Status:    StatusUp,
+		Required:  check.Required,
+		LatencyMs: float64(latency.Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = errorKind(err)
+		slog.WarnContext(ctx, "readiness check failed",
+			"check", check.Name,
+			"required", check.Required,
+			"latency", latency,
+			"error", err)
+	}
+	return result
+}
+
+// errorKind names a probe failure without the upstream URL, address or body.
+func errorKind(err error) string {
+	var upErr *upstream.Error
+	switch {
+	case errors.As(err, &upErr):
+		return upErr.Kind.String()
+	case errors.Is(err, upstream.ErrNotConfigured):
+		return "not configured"
+	case errors.Is(err, context.DeadlineExceeded):
+		return upstream.KindTimeout.String()
+	default:
+		return upstream.KindUnknown.String()
+	}
+}
@@ -0,0 +1,126 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/upstream"
+)
+
+func up(context.Context) error { return nil }
+
+func down(context.Context) error {
+	return &upstream.Error{Upstream: "catalog", Kind: upstream.KindUnavailable,
+		Err: errors.New("dial tcp 10.0.0.7:8080: connection refused")}
+}
+
+func TestRunAggregation(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []Check
+		ready  bool
+		status string
+	}{
+		{"all up", []Check{{Name: "catalog", Required: true, Probe: up}, {Name: "ai", Probe: up}}, true, StatusUp},
+		{"optional down", []Check{{Name: "catalog", Required: true, Probe: up}, {Name: "ai", Probe: down}}, true, StatusUp},
+		{"required down", []Check{{Name: "catalog", Required: true, Probe: down}, {Name: "ai", Probe: up}}, false, StatusDown},
+		{"no checks", nil, true, StatusUp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, ready := NewChecker(time.Second, 0, tt.checks...).Run(context.Background())
+			if ready != tt.ready || report.Status != tt.status {
+				t.Fatalf("Run() = %s, %v, want %s, %v", report.Status, ready, tt.status, tt.ready)
+			}
+			if len(report.Dependencies) != len(tt.checks) {
+				t.Fatalf("%d dependencies reported, want %d", len(report.Dependencies), len(tt.checks))
+			}
+		})
+	}
+}
+
+func TestRunHidesErrorDetails(t *testing.T) {
+	report, _ := NewChecker(time.Second, 0, Check{Name: "catalog", Required: true, Probe: down}).Run(context.Background())
+	result := report.Dependencies[0]
+	if result.Status != StatusDown || result.Error != "unavailable" {
+		t.Fatalf("result = %+v, want down with error %q", result, "unavailable")
+	}
+	if strings.Contains(fmt.Sprintf("%+v", result), "10.0.0.7") {
+		t.Fatalf("the upstream address leaked into the report: %+v", result)
+	}
+
+	report, _ = NewChecker(time.Second, 0, Check{Name: "ai", Probe: func(context.Context) error {
+		return upstream.ErrNotConfigured
+	}}).Run(context.Background())
+	if got := report.Dependencies[0].Error; got != "not configured" {
+		t.Fatalf("Error = %q, want %q", got, "not configured")
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	start := time.Now()
+	report, ready := NewChecker(20*time.Millisecond, 0,
+		Check{Name: "catalog", Required: true, Probe: slow},
+		Check{Name: "auth", Required: true, Probe: up},
+	).Run(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Run() took %v with a 20ms timeout", elapsed)
+	}
+	if ready {
+		t.Fatal("Run() ready with a required check timing out")
+	}
+	catalog := report.Dependencies[0]
+	if catalog.Error != "timeout" || catalog.LatencyMs < 20 {
+		t.Fatalf("slow check = %+v, want a timeout after at least 20ms", catalog)
+	}
+	if report.Dependencies[1].Status != StatusUp {
+		t.Fatalf("fast check = %+v, want up", report.Dependencies[1])
+	}
+}
+
+func TestRunKeepsLastError(t *testing.T) {
+	failing := true
+	checker := NewChecker(time.Second, 0, Check{Name: "catalog", Required: true, Probe: func(ctx context.Context) error {
+		if failing {
+			return down(ctx)
+		}
+		return nil
+	}})
+
+	checker.Run(context.Background())
+	failing = false
+	report, ready := checker.Run(context.Background())
+	result := report.Dependencies[0]
+	if !ready || result.Error != "" {
+		t.Fatalf("recovered check = %+v, ready %v", result, ready)
+	}
+	if result.LastError != "unavailable" || result.LastErrorAt == nil {
+		t.Fatalf("LastError = %q at %v, want the earlier failure", result.LastError, result.LastErrorAt)
+	}
+}
+
+func TestRunReusesReportAndDrains(t *testing.T) {
+	probes := 0
+	checker := NewChecker(time.Second, time.Minute, Check{Name: "catalog", Required: true, Probe: func(context.Context) error {
+		probes++
+		return nil
+	}})
+	checker.Run(context.Background())
+	checker.Run(context.Background())
+	if probes != 1 {
+		t.Fatalf("probed %d times within the ttl, want 1", probes)
+	}
+
+	checker.Drain()
+	if report, ready := checker.Run(context.Background()); ready || report.Status != StatusDraining {
+		t.Fatalf("Run() after Drain = %s, %v", report.Status, ready)
+	}
+}
@@ -2,9 +2,10 @@ package http
 
 import (
 	"database/sql"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/config"
 	"github.com/gin-gonic/gin"
 )
 
-func InitRouter(eng *gin.Engine, db *sql.DB) Router {
-	return &router{eng: eng, db: db}
+func InitRouter(eng *gin.Engine, db *sql.DB, cfg *config.Config) Router {
+	return &router{eng: eng, db: db, cfg: cfg}
 }
@@ -1,99 +1,304 @@
 package http
 
 import (
+	"context"
 	"database/sql"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/apikeys"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/cache"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/conditional"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/config"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/controllers"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/controllers/authcontroller"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/controllers/catalogcontroller"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/controllers/postcontroller"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/controllers/searchcontroller"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/cors"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/defines"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/graph"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/health"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/metrics"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/middleware"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/policy"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/ratelimit"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/repository/authrepository"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/repository/catalogrepository"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/repository/postrepository"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/search"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/services/authservice"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/services/catalogservice"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/services/postservice"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/services/searchservice"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/tokens"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/upstream"
 	"github.com/gin-gonic/gin"
 	"github.com/graphql-go/graphql"
 	"github.com/graphql-go/handler"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"time"
 )
 
+// maxSessions bounds the logins kept in memory; one that is evicted is only
+// held to its token's exp claim.
+const maxSessions = 10000
+
+// rateLimitSweep is how often idle rate limit buckets are dropped.
+const rateLimitSweep = time.Minute
+
 type Router interface {
-	MapRoutes([]string)
+	// MapRoutes registers every route; background work started for them,
+	// such as the search index refresh, stops when ctx ends.
+	MapRoutes(context.Context, *apikeys.Registry)
+	// Drain makes /readyz fail so the load balancer stops routing to us.
+	Drain()
 }
 
-type router struct {
-	eng *gin.Engine
-	db  *sql.DB
-}
+type (
+	router struct {
+		eng       *gin.Engine
+		api       *gin.RouterGroup
+		db        *sql.DB
+		cfg       *config.Config
+		upstreams upstreams
+		readiness *health.Checker
+	}
+
+	upstreams struct {
+		catalog          *upstream.Client
+		auth             *upstream.Client
+		ai               *upstream.Client
+		imageRecognition *upstream.Client
+		scrapping        *upstream.Client
+		posts            *upstream.Client
+	}
+)
 
-func (r *router) MapRoutes(validAPIKeys []string) {
-	r.setGroup(validAPIKeys)
+func (r *router) MapRoutes(ctx context.Context, apiKeys *apikeys.Registry) {
+	r.setGroup(apiKeys)
+	r.buildUpstreams()
 	r.addSystemPaths()
-	r.buildRoutes()
+	r.buildRoutes(ctx)
+}
+
+func (r *router) setGroup(apiKeys *apikeys.Registry) {
+	corsPolicy, err := cors.NewPolicy(r.cfg.CORS)
+	if err != nil {
+		panic(err)
+	}
+	corsPolicy.AlsoAllow(apiKeys.KnownOrigin)
+	r.eng.Use(middleware.RequestID(), otelgin.Middleware(r.cfg.Tracing.ServiceName), middleware.AccessLog(), middleware.Metrics(), middleware.Recovery(), middleware.CORS(corsPolicy))
+	// Todo menos las sondas de salud exige api key.
+	r.api = r.eng.Group("", middleware.ValidateAPIKey(apiKeys))
+}
+
+func (r *router) buildUpstreams() {
+	cfg := r.cfg.Upstreams
+	r.upstreams = upstreams{
+		catalog:          mustClient(cfg.Catalog),
+		auth:             mustClient(cfg.Auth),
+		ai:               mustClient(cfg.AI),
+		imageRecognition: mustClient(cfg.ImageRecognition),
+		scrapping:        mustClient(cfg.Scrapping),
+		posts:            mustClient(cfg.Posts),
+	}
 }
 
-func (r *router) setGroup(apiKeys []string) {
-	r.eng.Use(middleware.CORS(), middleware.ValidateAPIKey(apiKeys))
+func mustClient(cfg upstream.Config) *upstream.Client {
+	client, err := upstream.NewClient(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return client
 }
 
-func (r *router) buildRoutes() {
-	catalogRepository := catalogrepository.NewCatalogRepository()
-	aiRepository := catalogrepository.NewAIRepository()
-	scrappingRepository := catalogrepository.NewScrappingRepository()
-	authRepository := authrepository.NewAuthRepository()
-	postsRepository := postrepository.NewCatalogRepository()
+// enabledUpstreams returns the clients of the upstreams turned on by the
+// feature toggles.
+func (r *router) enabledUpstreams() []*upstream.Client {
+	u := r.upstreams
+	byName := make(map[string]*upstream.Client)
+	for _, client := range []*upstream.Client{u.catalog, u.auth, u.ai, u.imageRecognition, u.scrapping, u.posts} {
+		byName[client.Name()] = client
+	}
 
-	catalogService := catalogservice.NewCatalogService(catalogRepository)
+	var clients []*upstream.Client
+	for _, cfg := range r.cfg.Upstreams.Enabled(r.cfg.Features) {
+		clients = append(clients, byName[cfg.Name])
+	}
+	return clients
+}
+
+func (r *router) buildRoutes(ctx context.Context) {
+	catalogRepository := catalogrepository.NewCatalogRepository(r.upstreams.catalog)
+	if r.cfg.Cache.Enabled {
+		catalogCache := cache.New("catalog", cache.NewLRU(r.cfg.Cache.MaxEntries), r.cfg.Upstreams.Catalog.Timeout)
+		catalogRepository = catalogrepository.NewCachedCatalog(catalogRepository, catalogCache, catalogrepository.CacheTTLs{
+			Liquors: r.cfg.Cache.Liquors,
+			Recipes: r.cfg.Cache.Recipes,
+		})
+	}
+	aiRepository := catalogrepository.NewAIRepository(r.upstreams.ai, r.upstreams.imageRecognition)
+	scrappingRepository := catalogrepository.NewScrappingRepository(r.upstreams.scrapping)
+	authRepository := authrepository.NewAuthRepository(r.upstreams.auth)
+	postsRepository := postrepository.NewCatalogRepository(r.upstreams.posts)
+
+	var searchChanges *search.Changes
+	if r.cfg.Features.Search {
+		searchChanges = search.NewChanges()
+	}
+	catalogService := catalogservice.NewCatalogService(catalogRepository, catalogservice.Options{
+		ListPassthrough: r.cfg.Upstreams.CatalogListQuery,
+		Changes:         searchChanges,
+	})
 	aiService := catalogservice.NewAIService(aiRepository)
 	scrappingService := catalogservice.NewScrappingService(scrappingRepository)
-	authService := authservice.NewAuthService(authRepository)
-	postsService := postservice.NewPostsService(postsRepository)
+	tokenConfig := r.cfg.Auth
+	verifier, err := tokens.NewVerifier(tokenConfig, func(ctx context.Context) ([]byte, error) {
+		return authRepository.FetchJWKS(ctx, tokenConfig.JWKSPath)
+	})
+	if err != nil {
+		panic(err)
+	}
+	authService := authservice.NewAuthService(authRepository, authservice.Options{
+		Verifier:       verifier,
+		RemoteFallback: tokenConfig.RemoteFallback,
+		CacheTTL:       tokenConfig.CacheTTL,
+		Sessions:       cache.NewLRU(maxSessions),
+		SessionMaxAge:  tokenConfig.SessionMaxAge,
+	})
+	postsService := postservice.NewPostsService(postsRepository, postservice.Options{Changes: searchChanges})
+	var searchService searchservice.ISearch
+	if r.cfg.Features.Search {
+		searchService = searchservice.NewSearchService(catalogService, postsService, searchservice.Options{
+			RefreshInterval: r.cfg.Search.RefreshInterval,
+			Changes:         searchChanges,
+		})
+		searchService.Warm(ctx)
+	}
+
+	var limiter *ratelimit.Limiter
+	if r.cfg.RateLimit.Enabled {
+		store := ratelimit.NewMemoryStore()
+		store.SweepEvery(ctx, rateLimitSweep)
+		limiter = ratelimit.NewLimiter(store, r.cfg.RateLimit.Limits)
+	}
 
 	catalogController := catalogcontroller.NewLiquorController(catalogService)
 	aiController := catalogcontroller.NewAIController(aiService)
 	scrappingController := catalogcontroller.NewScrappingController(scrappingService)
 	authController := authcontroller.NewAuthController(authService)
-	//postController := postcontroller.NewPostsController(postsService)
+	postController := postcontroller.NewPostsController(postsService)
+
+	// Las lecturas son públicas; las escrituras requieren un usuario logueado.
+	// IA, OCR y scrapping tienen su propio presupuesto de requests. El límite
+	// por api key e IP va antes de verificar el token.
+	client := r.api.Group("", middleware.RateLimitClient(limiter))
+	public := client.Group("", middleware.OptionalAuth(authService), middleware.RateLimit(limiter, ratelimit.Cheap))
+	expensive := client.Group("", middleware.OptionalAuth(authService), middleware.RateLimit(limiter, ratelimit.Expensive))
+	authenticated := client.Group("", middleware.RequireAuth(authService), middleware.RateLimit(limiter, ratelimit.Cheap))
+
+	catalogRead := middleware.RequireScope(apikeys.ScopeCatalogRead)
+	catalogWrite := middleware.RequireScope(apikeys.ScopeCatalogWrite)
+	aiScope := middleware.RequireScope(apikeys.ScopeAI)
+	authScope := middleware.RequireScope(apikeys.ScopeAuth)
+	graphqlScope := middleware.RequireScope(apikeys.ScopeGraphQL)
+	validators := conditional.Validators()
 
 	// REST Licores
-	r.eng.GET("/liquors", catalogController.GetLiquors())
-	r.eng.GET("/liquors/:id", catalogController.GetLiquorByID())
-	r.eng.POST("/liquors", catalogController.CreateLiquor())
-	r.eng.PUT("/liquors/:id", catalogController.UpdateLiquor())
-	r.eng.DELETE("/liquors/:id", catalogController.DeleteLiquor())
+	public.GET("/liquors", catalogRead, validators, catalogController.GetLiquors())
+	public.GET("/liquors/:id", catalogRead, validators, catalogController.GetLiquorByID())
+	authenticated.POST("/liquors", catalogWrite, middleware.Authorize(policy.LiquorWrite), catalogController.CreateLiquor())
+	authenticated.PUT("/liquors/:id", catalogWrite, middleware.Authorize(policy.LiquorWrite), validators, catalogController.UpdateLiquor())
+	authenticated.DELETE("/liquors/:id", catalogWrite, middleware.Authorize(policy.LiquorWrite), catalogController.DeleteLiquor())
 
 	// REST Recetas
-	r.eng.GET("/recipes", catalogController.GetRecipes())
-	r.eng.GET("/recipes/:id", catalogController.GetRecipeByID())
-	r.eng.POST("/recipes", catalogController.CreateRecipe())
-	r.eng.PUT("/recipes/:id", catalogController.UpdateRecipe())
-	r.eng.DELETE("/recipes/:id", catalogController.DeleteRecipe())
+	public.GET("/recipes", catalogRead, validators, catalogController.GetRecipes())
+	public.GET("/recipes/:id", catalogRead, validators, catalogController.GetRecipeByID())
+	// La autoría de las recetas la valida catalogService.
+	authenticated.POST("/recipes", catalogWrite, catalogController.CreateRecipe())
+	authenticated.PUT("/recipes/:id", catalogWrite, validators, catalogController.UpdateRecipe())
+	authenticated.DELETE("/recipes/:id", catalogWrite, catalogController.DeleteRecipe())
+
+	// REST Posts
+	public.GET("/posts", catalogRead, validators, postController.GetPosts())
+	public.GET("/posts/:id", catalogRead, validators, postController.GetPostByID())
+	// La autoría de los posts la valida postsService.
+	authenticated.POST("/posts", catalogWrite, postController.CreatePost())
+	authenticated.PUT("/posts/:id", catalogWrite, validators, postController.UpdatePost())
+	authenticated.DELETE("/posts/:id", catalogWrite, postController.DeletePost())
+	// El usuario de cada interacción sale del token.
+	authenticated.PUT("/posts/:id/like", catalogWrite, postController.Like())
+	authenticated.DELETE("/posts/:id/like", catalogWrite, postController.Unlike())
+	authenticated.POST("/posts/:id/comments", catalogWrite, postController.Comment())
+	authenticated.DELETE("/posts/:id/comments/:createdAt", catalogWrite, postController.DeleteComment())
+	authenticated.PUT("/posts/:id/reactions/:reaction", catalogWrite, postController.React())
+	authenticated.DELETE("/posts/:id/reactions/:reaction", catalogWrite, postController.Unreact())
+
+	// REST Búsqueda
+	if searchService != nil {
+		searchController := searchcontroller.NewSearchController(searchService)
+		public.GET("/search", catalogRead, searchController.Search())
+	}
 
 	// REST AI & Scrapping
-	r.eng.POST("/processStrings", aiController.ProcessStrings())
-	r.eng.POST("/createAIRecipe", aiController.CreateRecipe())
-	r.eng.GET("/product/:code", scrappingController.GetProductByCode())
+	if r.cfg.Features.AI {
+		expensive.POST("/processStrings", aiScope, aiController.ProcessStrings())
+		expensive.POST("/createAIRecipe", aiScope, aiController.CreateRecipe())
+	}
+	if r.cfg.Features.Scrapping {
+		expensive.GET("/product/:code", aiScope, scrappingController.GetProductByCode())
+	}
 
 	// REST Auth
-	r.eng.GET("/verify", authController.Verify())
-	r.eng.POST("/register", authController.Register())
-	r.eng.POST("/login", authController.Login())
+	public.GET("/verify", authScope, authController.Verify())
+	public.POST("/register", authScope, authController.Register())
+	public.POST("/login", authScope, authController.Login())
 
 	// GraphQL Config
-	schema, err := graphql.NewSchema(graph.NewSchema(catalogService, authService, scrappingService, aiService, postsService))
+	schema, err := graphql.NewSchema(graph.NewSchema(catalogService, authService, scrappingService, aiService, postsService, searchService, limiter, r.cfg.Features))
 	if err != nil {
 		panic(err)
 	}
 	h := handler.New(&handler.Config{
 		Schema:   &schema,
 		Pretty:   true,
-		GraphiQL: true,
+		GraphiQL: r.cfg.Features.GraphiQL,
 	})
-	r.eng.GET("/graphql", gin.WrapH(h))
-	r.eng.POST("/graphql", gin.WrapH(h))
+	public.GET("/graphql", graphqlScope, middleware.GraphQLMetrics(&schema), gin.WrapH(h))
+	public.POST("/graphql", graphqlScope, middleware.GraphQLMetrics(&schema), gin.WrapH(h))
 }
 func (r *router) addSystemPaths() {
-	r.eng.GET(defines.PingPath, controllers.Ping())
+	r.eng.GET(defines.HealthzPath, controllers.Healthz())
+	r.readiness = r.readinessChecker()
+	r.eng.GET(defines.ReadyzPath, controllers.Readyz(r.readiness))
+
+	r.api.GET(defines.PingPath, controllers.Ping())
+	r.api.GET(defines.CircuitsPath, controllers.Circuits(r.enabledUpstreams()))
+	// Con METRICS_ADDR las métricas se sirven aparte (ver main).
+	if r.cfg.Metrics.Addr == "" {
+		r.api.GET(metrics.Path, gin.WrapH(metrics.Handler()))
+	}
+}
+
+func (r *router) Drain() {
+	if r.readiness != nil {
+		r.readiness.Drain()
+	}
+}
+
+// readinessChecker probes every enabled upstream; the ones listed in
+// READYZ_REQUIRED make the gateway unready when down.
+func (r *router) readinessChecker() *health.Checker {
+	required := make(map[string]bool)
+	for _, name := range r.cfg.Readiness.Required {
+		required[name] = true
+	}
+
+	var checks []health.Check
+	for _, client := range r.enabledUpstreams() {
+		checks = append(checks, health.Check{
+			Name:     client.Name(),
+			Required: required[client.Name()],
+			Probe:    client.Probe,
+		})
+	}
+	return health.NewChecker(r.cfg.Readiness.Timeout, r.cfg.Readiness.CacheTTL, checks...)
 }
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/certs"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/config"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// NewServer applies the server limits; tlsConfig is nil for plain HTTP.
+func NewServer(handler http.Handler, cfg config.Server, tlsConfig *tls.Config) *http.Server {
+	srv := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		TLSConfig:         tlsConfig,
+	}
+	if tlsConfig != nil && !slices.Contains(tlsConfig.NextProtos, "h2") {
+		// Un mapa vacío desactiva HTTP/2.
+		srv.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+	return srv
+}
+
+// TLSConfig builds the server TLS settings around the reloadable certificates.
+func TLSConfig(cfg config.TLS, reloader *certs.Reloader) *tls.Config {
+	base := &tls.Config{
+		MinVersion: cfg.MinVersion,
+		ClientAuth: cfg.ClientAuth,
+		NextProtos: []string{"http/1.1"},
+	}
+	if cfg.HTTP2 {
+		base.NextProtos = []string{"h2", "http/1.1"}
+	}
+	return reloader.TLSConfig(base)
+}
+
+// Serve runs srv until ctx is cancelled and then shuts down in two steps:
+// /readyz starts failing for DrainDelay so the load balancer takes us out,
+// and then the listener closes and in-flight requests get ShutdownTimeout
+// to finish before their connections are closed.
+func Serve(ctx context.Context, srv *http.Server, router Router, cfg config.Server) error {
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	return serve(ctx, srv, ln, router, cfg)
+}
+
+func serve(ctx context.Context, srv *http.Server, ln net.Listener, router Router, cfg config.Server) error {
+	// Serve escribe en srv.TLSConfig al arrancar; se lee antes.
+	useTLS := srv.TLSConfig != nil
+	errCh := make(chan error, 1)
+	go func() {
+		if useTLS {
+			errCh <- srv.ServeTLS(ln, "", "")
+			return
+		}
+		errCh <- srv.Serve(ln)
+	}()
+	slog.Info("server started", "addr", ln.Addr().String(), "tls", useTLS)
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down: draining", "drain_delay", cfg.DrainDelay.String())
+	router.Drain()
+	time.Sleep(cfg.DrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	slog.Info("shutting down: waiting for in-flight requests", "timeout", cfg.ShutdownTimeout.String())
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("in-flight requests did not finish in time, closing connections", "error", err)
+		srv.Close()
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	slog.Info("server stopped")
+	return nil
+}
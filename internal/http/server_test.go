@@ -0,0 +1,127 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/apikeys"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/config"
+)
+
+type drainRouter struct {
+	drained atomic.Bool
+}
+
+func (r *drainRouter) MapRoutes(context.Context, *apikeys.Registry) {}
+
+func (r *drainRouter) Drain() {
+	r.drained.Store(true)
+}
+
+// startServer serves handler on a local port until the returned cancel is
+// called; done gets what serve returned.
+func startServer(t *testing.T, handler http.Handler, cfg config.Server) (url string, router *drainRouter, cancel context.CancelFunc, done chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	router = &drainRouter{}
+	done = make(chan error, 1)
+	go func() {
+		done <- serve(ctx, NewServer(handler, cfg, nil), ln, router, cfg)
+	}()
+	return "http://" + ln.Addr().String(), router, cancel, done
+}
+
+func testServerConfig(drain, shutdown time.Duration) config.Server {
+	return config.Server{
+		ReadTimeout:     time.Second,
+		WriteTimeout:    5 * time.Second,
+		IdleTimeout:     time.Second,
+		MaxHeaderBytes:  1 << 10,
+		DrainDelay:      drain,
+		ShutdownTimeout: shutdown,
+	}
+}
+
+func TestServeFinishesInFlightRequests(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	})
+	url, router, cancel, done := startServer(t, handler, testServerConfig(50*time.Millisecond, 5*time.Second))
+
+	result := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNoContent {
+				err = errors.New(resp.Status)
+			}
+		}
+		result <- err
+	}()
+	<-started
+	cancel()
+
+	// While draining /readyz already fails and the request keeps running.
+	time.Sleep(20 * time.Millisecond)
+	if !router.drained.Load() {
+		t.Fatal("Drain() was not called on shutdown")
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("serve() returned %v with a request in flight", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-result; err != nil {
+		t.Fatalf("in-flight request failed: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("serve() = %v", err)
+	}
+}
+
+func TestServeClosesRequestsPastTheShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+	url, _, cancel, done := startServer(t, handler, testServerConfig(0, 50*time.Millisecond))
+
+	result := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		result <- err
+	}()
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("serve() = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("serve() did not give up on the stuck request")
+	}
+	if err := <-result; err == nil {
+		t.Fatal("stuck request got a response, want its connection closed")
+	}
+}
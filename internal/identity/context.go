@@ -0,0 +1,17 @@
+package identity
+
+import (
+	"context"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+)
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, id *entities.Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+func FromContext(ctx context.Context) (*entities.Identity, bool) {
+	id, ok := ctx.Value(contextKey{}).(*entities.Identity)
+	return id, ok && id != nil
+}
@@ -0,0 +1,61 @@
+package listing
+
+import (
+	"cmp"
+	"errors"
+	"strings"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+)
+
+var liquorSorts = map[string]func(a, b entities.Liquor) int{
+	SortName: func(a, b entities.Liquor) int { return compareFold(a.Name, b.Name) },
+}
+
+var recipeSorts = map[string]func(a, b entities.Recipe) int{
+	SortName:          func(a, b entities.Recipe) int { return compareFold(a.Name, b.Name) },
+	SortCreatedAt:     func(a, b entities.Recipe) int { return cmp.Compare(a.CreatedAt, b.CreatedAt) },
+	SortAverageRating: func(a, b entities.Recipe) int { return cmp.Compare(a.AverageRating, b.AverageRating) },
+	SortLikes:         func(a, b entities.Recipe) int { return cmp.Compare(a.Likes, b.Likes) },
+}
+
+// Liquors only supports the category filter, since liquors have no creator,
+// liquors or ratings.
+func Liquors(items []entities.Liquor, q Query) ([]entities.Liquor, Page, error) {
+	f := q.Filter
+	if f.Liquor != "" || f.CreatorID != "" || f.MinRating != nil {
+		return nil, Page{}, errors.New("liquors can only be filtered by category")
+	}
+	return apply(items, q, func(l entities.Liquor) bool {
+		return f.Category == "" || strings.EqualFold(l.Category, f.Category)
+	}, liquorSorts)
+}
+
+func Recipes(items []entities.Recipe, q Query) ([]entities.Recipe, Page, error) {
+	f := q.Filter
+	return apply(items, q, func(r entities.Recipe) bool {
+		if f.Category != "" && !strings.EqualFold(r.Category, f.Category) {
+			return false
+		}
+		if f.CreatorID != "" && r.CreatorId != f.CreatorID {
+			return false
+		}
+		if f.MinRating != nil && r.AverageRating < *f.MinRating {
+			return false
+		}
+		return f.Liquor == "" || containsFold(r.Liquors, f.Liquor)
+	}, recipeSorts)
+}
+
+func compareFold(a, b string) int {
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+	return false
+}
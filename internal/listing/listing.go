@@ -0,0 +1,244 @@
+package listing
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MaxLimit caps a page; a query without limit returns every item, as the
+// listings did before pagination.
+const MaxLimit = 200
+
+// Campos de orden admitidos.
+const (
+	SortName          = "name"
+	SortCreatedAt     = "createdAt"
+	SortAverageRating = "averageRating"
+	SortLikes         = "likes"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type (
+	Query struct {
+		Limit  int
+		Offset int
+		// Cursor continues a previous page and wins over Offset.
+		Cursor string
+		Sort   string
+		Desc   bool
+		Filter Filter
+	}
+
+	Filter struct {
+		Category  string
+		Liquor    string
+		CreatorID string
+		MinRating *float64
+	}
+
+	// Page describes the slice returned and how to ask for the next one.
+	Page struct {
+		Total      int    `json:"total"`
+		Limit      int    `json:"limit"`
+		Offset     int    `json:"offset"`
+		NextCursor string `json:"next_cursor,omitempty"`
+	}
+
+	cursor struct {
+		Offset      int    `json:"o"`
+		Limit       int    `json:"l"`
+		Fingerprint string `json:"f"`
+	}
+)
+
+// FromValues reads limit, offset, cursor, sort (a leading "-" or
+// order=desc sorts descending), category, liquor, creatorId and minRating.
+func FromValues(values url.Values) (Query, error) {
+	q := Query{
+		Cursor: values.Get("cursor"),
+		Sort:   values.Get("sort"),
+		Filter: Filter{
+			Category:  values.Get("category"),
+			Liquor:    values.Get("liquor"),
+			CreatorID: values.Get("creatorId"),
+		},
+	}
+	var err error
+	if q.Limit, err = intValue(values, "limit"); err != nil {
+		return q, err
+	}
+	if q.Offset, err = intValue(values, "offset"); err != nil {
+		return q, err
+	}
+	if strings.HasPrefix(q.Sort, "-") {
+		q.Sort, q.Desc = strings.TrimPrefix(q.Sort, "-"), true
+	}
+	switch order := strings.ToLower(values.Get("order")); order {
+	case "":
+	case "asc":
+		q.Desc = false
+	case "desc":
+		q.Desc = true
+	default:
+		return q, fmt.Errorf("order must be asc or desc, got %q", order)
+	}
+	if raw := values.Get("minRating"); raw != "" {
+		rating, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return q, fmt.Errorf("minRating must be a number, got %q", raw)
+		}
+		q.Filter.MinRating = &rating
+	}
+	return q, nil
+}
+
+// Values is the sorting and filtering part of q, as forwarded to a catalog
+// service that applies them itself. Pagination always stays in the gateway
+// so totals and cursors are computed the same way.
+func (q Query) Values() url.Values {
+	values := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	set("sort", q.Sort)
+	if q.Sort != "" && q.Desc {
+		values.Set("order", "desc")
+	}
+	set("category", q.Filter.Category)
+	set("liquor", q.Filter.Liquor)
+	set("creatorId", q.Filter.CreatorID)
+	if q.Filter.MinRating != nil {
+		values.Set("minRating", strconv.FormatFloat(*q.Filter.MinRating, 'f', -1, 64))
+	}
+	return values
+}
+
+// Resolve checks the bounds of q and turns its cursor into Offset and
+// Limit, so the page can also be asked to a catalog service.
+func (q Query) Resolve() (Query, error) {
+	if q.Limit < 0 || q.Offset < 0 {
+		return q, errors.New("limit and offset must not be negative")
+	}
+	if q.Limit > MaxLimit {
+		return q, fmt.Errorf("limit must not exceed %d", MaxLimit)
+	}
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor, q.fingerprint())
+		if err != nil {
+			return q, err
+		}
+		q.Offset = c.Offset
+		if q.Limit == 0 {
+			q.Limit = c.Limit
+		}
+		q.Cursor = ""
+	}
+	return q, nil
+}
+
+// PageValues is Values plus limit and offset, for a catalog service that
+// paginates. Call it on a resolved query.
+func (q Query) PageValues() url.Values {
+	values := q.Values()
+	if q.Limit > 0 {
+		values.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Offset > 0 {
+		values.Set("offset", strconv.Itoa(q.Offset))
+	}
+	return values
+}
+
+// PageOf describes n items cut by a catalog service at q.Offset out of
+// total matches. q must be resolved.
+func PageOf(q Query, n, total int) Page {
+	page := Page{Total: total, Limit: q.Limit, Offset: q.Offset}
+	if end := q.Offset + n; q.Limit > 0 && end < total {
+		page.NextCursor = encodeCursor(cursor{Offset: end, Limit: q.Limit, Fingerprint: q.fingerprint()})
+	}
+	return page
+}
+
+// apply filters with match, sorts with the comparator named by q.Sort and
+// cuts the requested page.
+func apply[T any](items []T, q Query, match func(T) bool, sorts map[string]func(a, b T) int) ([]T, Page, error) {
+	q, err := q.Resolve()
+	if err != nil {
+		return nil, Page{}, err
+	}
+	var compare func(a, b T) int
+	if q.Sort != "" {
+		var ok bool
+		if compare, ok = sorts[q.Sort]; !ok {
+			return nil, Page{}, fmt.Errorf("cannot sort by %q", q.Sort)
+		}
+	}
+
+	filtered := make([]T, 0, len(items))
+	for _, item := range items {
+		if match(item) {
+			filtered = append(filtered, item)
+		}
+	}
+	if compare != nil {
+		slices.SortStableFunc(filtered, func(a, b T) int {
+			if q.Desc {
+				return compare(b, a)
+			}
+			return compare(a, b)
+		})
+	}
+
+	start := min(q.Offset, len(filtered))
+	end := len(filtered)
+	if q.Limit > 0 {
+		end = min(start+q.Limit, len(filtered))
+	}
+	return filtered[start:end], PageOf(q, end-start, len(filtered)), nil
+}
+
+// fingerprint ties a cursor to the sort and filters it was issued for.
+func (q Query) fingerprint() string {
+	sum := sha256.Sum256([]byte(q.Values().Encode()))
+	return hex.EncodeToString(sum[:6])
+}
+
+func encodeCursor(c cursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(value, fingerprint string) (cursor, error) {
+	var c cursor
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || json.Unmarshal(raw, &c) != nil || c.Offset < 0 || c.Limit < 0 || c.Limit > MaxLimit {
+		return c, ErrInvalidCursor
+	}
+	if c.Fingerprint != fingerprint {
+		return c, fmt.Errorf("%w: it was issued for other sort or filters", ErrInvalidCursor)
+	}
+	return c, nil
+}
+
+func intValue(values url.Values, key string) (int, error) {
+	raw := values.Get(key)
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer, got %q", key, raw)
+	}
+	return n, nil
+}
@@ -0,0 +1,219 @@
+package listing
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+)
+
+var testLiquors = []entities.Liquor{
+	{Name: "Tequila", Category: "Agave"},
+	{Name: "aguardiente", Category: "Anise"},
+	{Name: "Mezcal", Category: "agave"},
+	{Name: "Ron", Category: "Rum"},
+	{Name: "Brandy", Category: "Brandy"},
+}
+
+func liquorNames(items []entities.Liquor) []string {
+	out := []string{}
+	for _, item := range items {
+		out = append(out, item.Name)
+	}
+	return out
+}
+
+func recipeNames(items []entities.Recipe) []string {
+	out := []string{}
+	for _, item := range items {
+		out = append(out, item.Name)
+	}
+	return out
+}
+
+func TestLiquorPages(t *testing.T) {
+	tests := []struct {
+		name    string
+		items   []entities.Liquor
+		query   Query
+		want    []string
+		page    Page
+		hasNext bool
+	}{
+		{"no query returns every item", testLiquors, Query{},
+			[]string{"Tequila", "aguardiente", "Mezcal", "Ron", "Brandy"}, Page{Total: 5}, false},
+		{"empty list", nil, Query{Limit: 10},
+			[]string{}, Page{Limit: 10}, false},
+		{"first page", testLiquors, Query{Limit: 2, Sort: SortName},
+			[]string{"aguardiente", "Brandy"}, Page{Total: 5, Limit: 2}, true},
+		{"last page", testLiquors, Query{Limit: 2, Offset: 4, Sort: SortName},
+			[]string{"Tequila"}, Page{Total: 5, Limit: 2, Offset: 4}, false},
+		{"page ending on the last item", testLiquors, Query{Limit: 5},
+			[]string{"Tequila", "aguardiente", "Mezcal", "Ron", "Brandy"}, Page{Total: 5, Limit: 5}, false},
+		{"offset past the end", testLiquors, Query{Limit: 2, Offset: 9},
+			[]string{}, Page{Total: 5, Limit: 2, Offset: 9}, false},
+		{"descending", testLiquors, Query{Limit: 2, Sort: SortName, Desc: true},
+			[]string{"Tequila", "Ron"}, Page{Total: 5, Limit: 2}, true},
+		{"category ignores case", testLiquors, Query{Filter: Filter{Category: "AGAVE"}},
+			[]string{"Tequila", "Mezcal"}, Page{Total: 2}, false},
+		{"no matches", testLiquors, Query{Filter: Filter{Category: "Gin"}},
+			[]string{}, Page{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, page, err := Liquors(tt.items, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(liquorNames(got), tt.want) {
+				t.Errorf("items = %v, want %v", liquorNames(got), tt.want)
+			}
+			if (page.NextCursor != "") != tt.hasNext {
+				t.Errorf("NextCursor = %q, want one: %v", page.NextCursor, tt.hasNext)
+			}
+			page.NextCursor = ""
+			if page != tt.page {
+				t.Errorf("page = %+v, want %+v", page, tt.page)
+			}
+		})
+	}
+}
+
+func TestLiquorsRejects(t *testing.T) {
+	for name, q := range map[string]Query{
+		"creator filter":         {Filter: Filter{CreatorID: "u1"}},
+		"unknown sort":           {Sort: SortLikes},
+		"negative limit":         {Limit: -1},
+		"negative offset":        {Offset: -1},
+		"limit over the maximum": {Limit: MaxLimit + 1},
+		"invalid cursor":         {Cursor: "nope"},
+	} {
+		if _, _, err := Liquors(testLiquors, q); err == nil {
+			t.Errorf("%s: Liquors() returned no error", name)
+		}
+	}
+}
+
+func TestCursorWalksEveryPage(t *testing.T) {
+	items := []entities.Recipe{
+		{Name: "A", Likes: 5}, {Name: "B", Likes: 1}, {Name: "C", Likes: 3},
+		{Name: "D", Likes: 4}, {Name: "E", Likes: 2},
+	}
+	query := Query{Limit: 2, Sort: SortLikes, Desc: true}
+
+	var seen []string
+	for pages := 0; ; pages++ {
+		if pages > len(items) {
+			t.Fatal("the cursor never ends")
+		}
+		got, page, err := Recipes(items, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen = append(seen, recipeNames(got)...)
+		if page.NextCursor == "" {
+			break
+		}
+		// The cursor keeps the limit when the next request leaves it out.
+		query = Query{Cursor: page.NextCursor, Sort: SortLikes, Desc: true}
+	}
+	if want := []string{"A", "D", "C", "E", "B"}; !slices.Equal(seen, want) {
+		t.Fatalf("pages = %v, want %v", seen, want)
+	}
+
+	_, page, _ := Recipes(items, Query{Limit: 2, Sort: SortLikes})
+	if _, _, err := Recipes(items, Query{Cursor: page.NextCursor, Sort: SortName}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("cursor reused with another sort: error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestRecipeFilters(t *testing.T) {
+	rating := 4.0
+	items := []entities.Recipe{
+		{Name: "Margarita", Category: "Sour", CreatorId: "u1", AverageRating: 4.5, Liquors: []string{"Tequila"}},
+		{Name: "Paloma", Category: "Highball", CreatorId: "u2", AverageRating: 3.9, Liquors: []string{"tequila"}},
+		{Name: "Mojito", Category: "Highball", CreatorId: "u1", AverageRating: 4.0, Liquors: []string{"Ron"}},
+	}
+	check := func(filter Filter, want ...string) {
+		t.Helper()
+		got, _, err := Recipes(items, Query{Filter: filter})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(recipeNames(got), want) {
+			t.Errorf("Recipes(%+v) = %v, want %v", filter, recipeNames(got), want)
+		}
+	}
+	check(Filter{Liquor: "TEQUILA"}, "Margarita", "Paloma")
+	check(Filter{CreatorID: "u1"}, "Margarita", "Mojito")
+	check(Filter{MinRating: &rating}, "Margarita", "Mojito")
+	check(Filter{Category: "highball", CreatorID: "u1", Liquor: "ron", MinRating: &rating}, "Mojito")
+}
+
+func TestFromValues(t *testing.T) {
+	valid := map[string]Query{
+		"":                         {},
+		"limit=10&offset=20":       {Limit: 10, Offset: 20},
+		"sort=-likes":              {Sort: SortLikes, Desc: true},
+		"sort=-likes&order=asc":    {Sort: SortLikes},
+		"sort=name&order=DESC":     {Sort: SortName, Desc: true},
+		"category=Sour&liquor=Ron": {Filter: Filter{Category: "Sour", Liquor: "Ron"}},
+	}
+	for raw, want := range valid {
+		values, _ := url.ParseQuery(raw)
+		got, err := FromValues(values)
+		if err != nil || got != want {
+			t.Errorf("FromValues(%q) = %+v, %v, want %+v", raw, got, err, want)
+		}
+	}
+
+	for _, raw := range []string{"order=sideways", "limit=ten", "offset=1.5", "minRating=high"} {
+		values, _ := url.ParseQuery(raw)
+		if _, err := FromValues(values); err == nil {
+			t.Errorf("FromValues(%q) returned no error", raw)
+		}
+	}
+}
+
+func TestPageOf(t *testing.T) {
+	next := func(q Query, n, total int) (Query, bool) {
+		t.Helper()
+		page := PageOf(q, n, total)
+		if page.Total != total || page.Offset != q.Offset || page.Limit != q.Limit {
+			t.Fatalf("PageOf() = %+v", page)
+		}
+		if page.NextCursor == "" {
+			return Query{}, false
+		}
+		resolved, err := Query{Cursor: page.NextCursor}.Resolve()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resolved, true
+	}
+
+	q, ok := next(Query{Limit: 10}, 10, 25)
+	if !ok || q.Offset != 10 || q.Limit != 10 {
+		t.Fatalf("after the first page: %+v, %v", q, ok)
+	}
+	if values := q.PageValues(); values.Get("offset") != "10" || values.Get("limit") != "10" {
+		t.Fatalf("PageValues() = %v", values)
+	}
+	if _, ok := next(Query{Limit: 10, Offset: 20}, 5, 25); ok {
+		t.Error("a short last page has a next cursor")
+	}
+	if _, ok := next(Query{Limit: 10, Offset: 10}, 10, 20); ok {
+		t.Error("a full last page has a next cursor")
+	}
+	if _, ok := next(Query{Limit: 10}, 0, 0); ok {
+		t.Error("an empty upstream page has a next cursor")
+	}
+	if _, ok := next(Query{Limit: 10, Offset: 30}, 0, 25); ok {
+		t.Error("an offset past the end has a next cursor")
+	}
+	if _, ok := next(Query{}, 25, 25); ok {
+		t.Error("a query without limit has a next cursor")
+	}
+}
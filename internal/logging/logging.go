@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/apikeys"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/identity"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/requestid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// contextHandler adds the request id, API key name, user id and trace found
+// in the context to every record, so callers only need to use the *Context methods.
+type contextHandler struct {
+	slog.Handler
+}
+
+// New builds a JSON logger writing to w at level ("debug", "info", "warn" or "error").
+func New(w io.Writer, level string) *slog.Logger {
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: parseLevel(level)})
+	return slog.New(contextHandler{handler})
+}
+
+// Setup installs the gateway logger as the slog and log package default.
+func Setup(level string) {
+	slog.SetDefault(New(os.Stdout, level))
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		if id := requestid.FromContext(ctx); id != "" {
+			record.AddAttrs(slog.String("request_id", id))
+		}
+		if key, ok := apikeys.FromContext(ctx); ok {
+			record.AddAttrs(slog.String("api_key", key.Name))
+		}
+		if user, ok := identity.FromContext(ctx); ok {
+			record.AddAttrs(slog.String("user_id", user.UserID))
+		}
+		if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+			record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+func parseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "gateway"
+	Path      = "/metrics"
+)
+
+// Registry holds every gateway metric plus the Go runtime and process collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by gin route and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time spent handling HTTP requests, by gin route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	HTTPInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests being handled.",
+	})
+
+	GraphQLOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "graphql_operations_total",
+		Help:      "GraphQL operations executed, by root field and type.",
+	}, []string{"operation", "type"})
+
+	GraphQLDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "graphql_operation_duration_seconds",
+		Help:      "Time spent executing GraphQL operations, by root field and type.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "type"})
+
+	UpstreamRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_requests_total",
+		Help:      "Calls to microservices, by upstream, method and status class (2xx..5xx, or error).",
+	}, []string{"upstream", "method", "status_class"})
+
+	UpstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Duration of calls to microservices, by upstream and method.",
+		Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 20, 30},
+	}, []string{"upstream", "method"})
+
+	UpstreamInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upstream_requests_in_flight",
+		Help:      "Calls to microservices waiting for a response.",
+	}, []string{"upstream"})
+
+	UpstreamTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_timeouts_total",
+		Help:      "Calls to microservices that timed out.",
+	}, []string{"upstream"})
+
+	CircuitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_circuit_rejections_total",
+		Help:      "Calls not sent because the upstream circuit was open.",
+	}, []string{"upstream"})
+
+	CircuitOpens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_circuit_opens_total",
+		Help:      "Times the upstream circuit went from closed or half-open to open.",
+	}, []string{"upstream"})
+
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Cache lookups, by cache and result (hit, stale or miss).",
+	}, []string{"cache", "result"})
+	CircuitState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upstream_circuit_state",
+		Help:      "Current circuit state: 0 closed, 1 open, 2 half-open.",
+	}, []string{"upstream"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests, HTTPDuration, HTTPInFlight,
+		GraphQLOperations, GraphQLDuration,
+		UpstreamRequests, UpstreamDuration, UpstreamInFlight, UpstreamTimeouts,
+		CircuitRejections, CircuitOpens, CircuitState,
+		CacheRequests,
+	)
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Serve exposes /metrics on its own listener, outside the API key check,
+// for scrapers on the internal network.
+func Serve(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(Path, Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("metrics server stopped", "addr", addr, "error", err)
+		}
+	}()
+	return srv
+}
+
+// StatusClass groups status codes as "2xx", "4xx"... to keep label cardinality low.
+func StatusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
@@ -1,13 +1,18 @@
 package middleware
 
 import (
+	"errors"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/apikeys"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/defines"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"strings"
 )
 
-func ValidateAPIKey(apiKeys []string) gin.HandlerFunc {
+// ValidateAPIKey looks the x-api-key header up in the registry and stores the
+// key in the gin and the request context so later handlers can log its name.
+func ValidateAPIKey(registry *apikeys.Registry) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		apiKey := c.GetHeader("x-api-key")
 		if apiKey == "" {
@@ -16,20 +21,55 @@ func ValidateAPIKey(apiKeys []string) gin.HandlerFunc {
 			return
 		}
 
-		isValid := false
-		for _, key := range apiKeys {
-			if key == apiKey {
-				isValid = true
-				break
+		key, err := registry.Lookup(apiKey)
+		if err != nil {
+			message := defines.InvalidApiKey
+			if errors.Is(err, apikeys.ErrExpiredKey) {
+				message = defines.ExpiredApiKey
 			}
+			utils.Error(c, http.StatusUnauthorized, message.Error())
+			c.Abort()
+			return
 		}
 
-		if !isValid {
-			utils.Error(c, http.StatusUnauthorized, defines.InvalidApiKey.Error())
+		// The CORS middleware sets the CORS headers, key origins included;
+		// here a browser on another origin is only turned away.
+		if len(key.Origins) > 0 {
+			addVary(c.Writer.Header(), "Origin")
+		}
+		if origin := c.GetHeader("Origin"); origin != "" && !key.AllowsOrigin(origin) {
+			utils.Error(c, http.StatusForbidden, defines.OriginNotAllowed.Error())
 			c.Abort()
 			return
 		}
 
+		c.Set(defines.APIKeyKey, key)
+		c.Request = c.Request.WithContext(apikeys.NewContext(c.Request.Context(), key))
+		c.Next()
+	}
+}
+
+// addVary adds value to Vary unless it is already there.
+func addVary(header http.Header, value string) {
+	for _, v := range header.Values("Vary") {
+		for _, field := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), value) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", value)
+}
+
+// RequireScope rejects API keys that were not granted scope.
+func RequireScope(scope apikeys.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := apikeys.FromContext(c.Request.Context())
+		if !ok || !key.Allows(scope) {
+			utils.Error(c, http.StatusForbidden, defines.ApiKeyScope.Error())
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
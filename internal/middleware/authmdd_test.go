@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/apikeys"
+	"github.com/gin-gonic/gin"
+)
+
+func testRegistry(t *testing.T) *apikeys.Registry {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	content := "keys:\n" +
+		"  - name: full\n    hash: " + apikeys.HashSecret("full") + "\n    scopes: [catalog:read, catalog:write]\n" +
+		"  - name: read\n    hash: " + apikeys.HashSecret("read") + "\n    scopes: [catalog:read]\n" +
+		"  - name: web\n    hash: " + apikeys.HashSecret("web") + "\n    scopes: [catalog:read]\n    origins: [https://web.example/]\n" +
+		"  - name: old\n    hash: " + apikeys.HashSecret("old") + "\n    scopes: [catalog:read]\n    expires_at: 2000-01-01T00:00:00Z\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	registry, err := apikeys.LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return registry
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	eng := gin.New()
+	api := eng.Group("", ValidateAPIKey(testRegistry(t)))
+	api.GET("/liquors", RequireScope(apikeys.ScopeCatalogRead), func(c *gin.Context) { c.Status(http.StatusOK) })
+	api.POST("/liquors", RequireScope(apikeys.ScopeCatalogWrite), func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	tests := []struct {
+		name   string
+		method string
+		key    string
+		origin string
+		want   int
+	}{
+		{"no key", http.MethodGet, "", "", http.StatusUnauthorized},
+		{"unknown key", http.MethodGet, "nope", "", http.StatusUnauthorized},
+		{"expired key", http.MethodGet, "old", "", http.StatusUnauthorized},
+		{"read with catalog:read", http.MethodGet, "read", "", http.StatusOK},
+		{"write without catalog:write", http.MethodPost, "read", "", http.StatusForbidden},
+		{"write with catalog:write", http.MethodPost, "full", "", http.StatusCreated},
+		{"key origin", http.MethodGet, "web", "https://web.example", http.StatusOK},
+		{"other origin for a key with origins", http.MethodGet, "web", "https://evil.example", http.StatusForbidden},
+		{"any origin for a key without origins", http.MethodGet, "read", "https://evil.example", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/liquors", nil)
+			if tt.key != "" {
+				req.Header.Set("x-api-key", tt.key)
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rec := httptest.NewRecorder()
+			eng.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+				t.Fatalf("the key check set Access-Control-Allow-Origin = %q", got)
+			}
+		})
+	}
+}
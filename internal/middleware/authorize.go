@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/Cococtel/Cococtel_Gagateway/internal/policy"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Authorize enforces a role rule on the identity stored by RequireAuth.
+// Rules that depend on who owns a resource are checked by the services.
+func Authorize(rule policy.Rule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := CurrentIdentity(c)
+		if apiErr := policy.Authorize(id, rule, ""); apiErr != nil {
+			utils.ApiErrorResponse(c, apiErr)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
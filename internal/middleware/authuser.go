@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/defines"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/identity"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/services/authservice"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+// RequireAuth rejects requests without a valid user token and stores the
+// caller's identity in both the gin and the request context.
+func RequireAuth(authService authservice.IAuth) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := UserToken(c)
+		if token == "" {
+			utils.ApiErrorResponse(c, utils.NewApiError(defines.MissingUserToken, http.StatusUnauthorized))
+			c.Abort()
+			return
+		}
+
+		if authenticate(c, authService, token) {
+			c.Next()
+		}
+	}
+}
+
+// UserToken reads the user token from x-auth-token or an Authorization bearer header.
+func UserToken(c *gin.Context) string {
+	if token := c.GetHeader("x-auth-token"); token != "" {
+		return token
+	}
+	header := c.GetHeader("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+func CurrentIdentity(c *gin.Context) (*entities.Identity, bool) {
+	value, exists := c.Get(defines.IdentityKey)
+	if !exists {
+		return nil, false
+	}
+	id, ok := value.(*entities.Identity)
+	return id, ok
+}
+
+// OptionalAuth attaches the caller's identity when a user token is sent and
+// lets requests without one through as anonymous. A token that fails
+// verification is rejected rather than ignored.
+func OptionalAuth(authService authservice.IAuth) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := UserToken(c)
+		if token == "" {
+			c.Next()
+			return
+		}
+		if authenticate(c, authService, token) {
+			c.Next()
+		}
+	}
+}
+
+// authenticate stores the identity behind token in both the gin and the
+// request context, or answers with the error and aborts.
+func authenticate(c *gin.Context, authService authservice.IAuth, token string) bool {
+	id, apiErr := authService.Authenticate(c.Request.Context(), token)
+	if apiErr != nil {
+		utils.ApiErrorResponse(c, apiErr)
+		c.Abort()
+		return false
+	}
+	if id.UserID == "" {
+		utils.ApiErrorResponse(c, utils.NewApiError(errors.New("token has no user"), http.StatusUnauthorized))
+		c.Abort()
+		return false
+	}
+
+	c.Set(defines.IdentityKey, id)
+	c.Request = c.Request.WithContext(identity.NewContext(c.Request.Context(), id))
+	return true
+}
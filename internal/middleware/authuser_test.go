@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/services/authservice"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeAuth accepts the tokens in identities and counts every verification.
+type fakeAuth struct {
+	authservice.IAuth
+	identities map[string]*entities.Identity
+	calls      int
+}
+
+func (a *fakeAuth) Authenticate(_ context.Context, token string) (*entities.Identity, utils.ApiError) {
+	a.calls++
+	if id, ok := a.identities[token]; ok {
+		return id, nil
+	}
+	return nil, utils.NewApiError(errors.New("invalid token"), http.StatusUnauthorized)
+}
+
+func TestUserToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"no headers", nil, ""},
+		{"x-auth-token", map[string]string{"x-auth-token": "t1"}, "t1"},
+		{"bearer", map[string]string{"Authorization": "Bearer t2"}, "t2"},
+		{"bearer in any case", map[string]string{"Authorization": "bearer  t3 "}, "t3"},
+		{"x-auth-token wins", map[string]string{"x-auth-token": "t1", "Authorization": "Bearer t2"}, "t1"},
+		{"other scheme", map[string]string{"Authorization": "Basic dTpw"}, ""},
+		{"empty bearer", map[string]string{"Authorization": "Bearer "}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/graphql", nil)
+			for name, value := range tt.headers {
+				c.Request.Header.Set(name, value)
+			}
+			if got := UserToken(c); got != tt.want {
+				t.Fatalf("UserToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptionalAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth := &fakeAuth{identities: map[string]*entities.Identity{
+		"valid":   {UserID: "u1"},
+		"no-user": {},
+	}}
+	eng := gin.New()
+	eng.GET("/liquors", OptionalAuth(auth), func(c *gin.Context) {
+		if id, ok := CurrentIdentity(c); ok {
+			c.String(http.StatusOK, id.UserID)
+			return
+		}
+		c.String(http.StatusOK, "anonymous")
+	})
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+		caller  string
+	}{
+		{"no token", nil, http.StatusOK, "anonymous"},
+		{"other scheme", map[string]string{"Authorization": "Basic dTpw"}, http.StatusOK, "anonymous"},
+		{"valid token", map[string]string{"x-auth-token": "valid"}, http.StatusOK, "u1"},
+		{"valid bearer", map[string]string{"Authorization": "Bearer valid"}, http.StatusOK, "u1"},
+		{"invalid token", map[string]string{"x-auth-token": "forged"}, http.StatusUnauthorized, ""},
+		{"invalid bearer", map[string]string{"Authorization": "Bearer forged"}, http.StatusUnauthorized, ""},
+		{"token without user", map[string]string{"x-auth-token": "no-user"}, http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/liquors", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			eng.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.caller != "" && rec.Body.String() != tt.caller {
+				t.Fatalf("caller = %q, want %q", rec.Body.String(), tt.caller)
+			}
+		})
+	}
+}
@@ -1,23 +1,52 @@
 package middleware
 
 import (
+	"github.com/Cococtel/Cococtel_Gagateway/internal/cors"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
-func CORS() gin.HandlerFunc {
+// CORS applies policy to browser requests. Preflights are answered here,
+// before the API key check, since browsers never send custom headers on them.
+func CORS(policy *cors.Policy) gin.HandlerFunc {
+	methods := strings.Join(policy.AllowedMethods, ", ")
+	headers := strings.Join(policy.AllowedHeaders, ", ")
+	exposed := strings.Join(policy.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(policy.MaxAge.Seconds()))
+
 	return func(ctx *gin.Context) {
-		ctx.Writer.Header().Add("Access-Control-Allow-Origin", "*")
-		ctx.Writer.Header().Add("Access-Control-Allow-Credentails", "true")
-		ctx.Writer.Header().Add("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, x-api-key, x-auth-key")
-		ctx.Writer.Header().Add("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+		origin := ctx.GetHeader("Origin")
+		preflight := ctx.Request.Method == http.MethodOptions && ctx.GetHeader("Access-Control-Request-Method") != ""
+		header := ctx.Writer.Header()
+		header.Add("Vary", "Origin")
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+		}
 
-		if ctx.Request.Method == "OPTIONS" {
-			http.Error(ctx.Writer, "No Content", http.StatusNoContent)
-			return
+		if policy.AllowOrigin(origin) {
+			header.Set("Access-Control-Allow-Origin", policy.AllowOriginValue(origin))
+			// Solo con un origen concreto; el navegador rechaza credenciales con "*".
+			if policy.AllowCredentials && policy.AllowOriginValue(origin) != cors.Wildcard {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if preflight {
+				header.Set("Access-Control-Allow-Methods", methods)
+				header.Set("Access-Control-Allow-Headers", headers)
+				if policy.MaxAge > 0 {
+					header.Set("Access-Control-Max-Age", maxAge)
+				}
+			} else if exposed != "" {
+				header.Set("Access-Control-Expose-Headers", exposed)
+			}
 		}
 
+		if preflight {
+			ctx.AbortWithStatus(http.StatusNoContent)
+			return
+		}
 		ctx.Next()
 	}
-
 }
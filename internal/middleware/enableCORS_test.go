@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/cors"
+	"github.com/gin-gonic/gin"
+)
+
+func corsRequest(t *testing.T, cfg cors.Config, method, origin string, preflight bool) *httptest.ResponseRecorder {
+	t.Helper()
+	policy, err := cors.NewPolicy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	eng := gin.New()
+	eng.Use(CORS(policy))
+	eng.GET("/liquors", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(method, "/liquors", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if preflight {
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	}
+	rec := httptest.NewRecorder()
+	eng.ServeHTTP(rec, req)
+	return rec
+}
+
+var testCORS = cors.Config{
+	AllowedOrigins:   []string{"https://cococtel.app", "https://*.cococtel.app"},
+	AllowedMethods:   []string{"GET", "POST"},
+	AllowedHeaders:   []string{"Content-Type", "x-api-key"},
+	ExposedHeaders:   []string{"ETag"},
+	AllowCredentials: true,
+	MaxAge:           10 * time.Minute,
+}
+
+func TestCORSPreflight(t *testing.T) {
+	rec := corsRequest(t, testCORS, http.MethodOptions, "https://admin.cococtel.app", true)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	want := map[string]string{
+		"Access-Control-Allow-Origin":      "https://admin.cococtel.app",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, POST",
+		"Access-Control-Allow-Headers":     "Content-Type, x-api-key",
+		"Access-Control-Max-Age":           "600",
+	}
+	for name, value := range want {
+		if got := rec.Header().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+	if vary := rec.Header().Values("Vary"); len(vary) != 3 {
+		t.Errorf("Vary = %v, want Origin and the two request headers", vary)
+	}
+
+	// A preflight from another origin still ends here, without CORS headers.
+	rec = corsRequest(t, testCORS, http.MethodOptions, "https://evil.example", true)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("disallowed preflight: status %d, Allow-Origin %q", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestCORSRequest(t *testing.T) {
+	rec := corsRequest(t, testCORS, http.MethodGet, "https://cococtel.app", false)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://cococtel.app" {
+		t.Errorf("Access-Control-Allow-Origin = %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Expose-Headers"); got != "ETag" {
+		t.Errorf("Access-Control-Expose-Headers = %q, want ETag", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "" {
+		t.Errorf("Access-Control-Allow-Methods = %q on a non-preflight request", got)
+	}
+
+	rec = corsRequest(t, testCORS, http.MethodGet, "https://evil.example", false)
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("disallowed origin: status %d, Allow-Origin %q", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if got := rec.Header().Get("Vary"); got != "Origin" {
+		t.Errorf("Vary = %q, want Origin", got)
+	}
+
+	// Without Access-Control-Request-Method an OPTIONS is routed as usual.
+	rec = corsRequest(t, testCORS, http.MethodOptions, "https://cococtel.app", false)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("plain OPTIONS: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	cfg := cors.Config{AllowedOrigins: []string{cors.Wildcard}, AllowedMethods: []string{"GET"}}
+	rec := corsRequest(t, cfg, http.MethodGet, "https://anyone.example", false)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != cors.Wildcard {
+		t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, cors.Wildcard)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Access-Control-Allow-Credentials = %q with a wildcard origin", got)
+	}
+}
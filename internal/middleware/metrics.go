@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/defines"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/metrics"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/handler"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxGraphQLBody bounds what GraphQLMetrics buffers to find the operation.
+const maxGraphQLBody = 1 << 20
+
+// Etiquetas fijas para operaciones que no llegan a un campo del schema.
+const (
+	operationInvalid       = "invalid"
+	operationOther         = "other"
+	operationIntrospection = "introspection"
+)
+
+// Metrics records count, latency and in-flight requests per gin route.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		metrics.HTTPInFlight.Inc()
+		c.Next()
+		metrics.HTTPInFlight.Dec()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		metrics.HTTPDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// GraphQLMetrics records count and latency per GraphQL operation, labelled
+// with the root field it resolves in schema. Operation names are chosen by
+// the client, so using them as labels would let anyone create new series.
+// It reads the body to find the operation and puts it back for the handler.
+func GraphQLMetrics(schema *graphql.Schema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name, kind, err := graphQLOperation(c, schema)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.ApiErrorResponse(c, utils.NewApiError(defines.BodyTooLarge, http.StatusRequestEntityTooLarge))
+			c.Abort()
+			return
+		}
+		start := time.Now()
+		c.Next()
+		metrics.GraphQLOperations.WithLabelValues(name, kind).Inc()
+		metrics.GraphQLDuration.WithLabelValues(name, kind).Observe(time.Since(start).Seconds())
+	}
+}
+
+func graphQLOperation(c *gin.Context, schema *graphql.Schema) (string, string, error) {
+	r := c.Request
+	if r.Body != nil {
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, r.Body, maxGraphQLBody))
+		r.Body.Close()
+		if err != nil {
+			return operationInvalid, "unknown", err
+		}
+		clone := r.Clone(r.Context())
+		clone.Body = io.NopCloser(bytes.NewReader(body))
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r = clone
+	}
+
+	opts := handler.NewRequestOptions(r)
+	doc, err := parser.Parse(parser.ParseParams{Source: opts.Query})
+	if err != nil {
+		return operationInvalid, "unknown", nil
+	}
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if opts.OperationName != "" && (op.Name == nil || op.Name.Value != opts.OperationName) {
+			continue
+		}
+		return rootField(schema, op), op.Operation, nil
+	}
+	return operationInvalid, "unknown", nil
+}
+
+// rootField is the first field op selects when schema has it, so the label
+// only takes values from the schema and a few fixed names.
+func rootField(schema *graphql.Schema, op *ast.OperationDefinition) string {
+	var root *graphql.Object
+	switch op.Operation {
+	case ast.OperationTypeQuery:
+		root = schema.QueryType()
+	case ast.OperationTypeMutation:
+		root = schema.MutationType()
+	}
+	if root == nil || op.SelectionSet == nil {
+		return operationOther
+	}
+	for _, selection := range op.SelectionSet.Selections {
+		field, ok := selection.(*ast.Field)
+		if !ok || field.Name == nil {
+			continue
+		}
+		name := field.Name.Value
+		if strings.HasPrefix(name, "__") {
+			return operationIntrospection
+		}
+		if _, known := root.Fields()[name]; known {
+			return name
+		}
+		return operationOther
+	}
+	return operationOther
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/metrics"
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func testSchema(t *testing.T) *graphql.Schema {
+	t.Helper()
+	field := func(name string) *graphql.Field {
+		return &graphql.Field{Type: graphql.String, Resolve: func(graphql.ResolveParams) (interface{}, error) { return name, nil }}
+	}
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query:    graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: graphql.Fields{"liquors": field("liquors")}}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{Name: "Mutation", Fields: graphql.Fields{"createLiquor": field("createLiquor")}}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &schema
+}
+
+func TestGraphQLMetricsLabels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	eng := gin.New()
+	var body string
+	eng.POST("/graphql", GraphQLMetrics(testSchema(t)), func(c *gin.Context) {
+		// The handler still gets the whole body.
+		raw, _ := io.ReadAll(c.Request.Body)
+		body = string(raw)
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		payload string
+		name    string
+		kind    string
+	}{
+		{`{"query":"query GetThem { liquors }"}`, "liquors", "query"},
+		{`{"query":"{ liquors }"}`, "liquors", "query"},
+		{`{"query":"mutation RandomName123 { createLiquor }"}`, "createLiquor", "mutation"},
+		{`{"query":"query A { liquors } mutation B { createLiquor }","operationName":"B"}`, "createLiquor", "mutation"},
+		{`{"query":"query Anything { unknownField }"}`, "other", "query"},
+		{`{"query":"{ __schema { queryType { name } } }"}`, "introspection", "query"},
+		{`{"query":"{ liquors"}`, "invalid", "unknown"},
+	}
+	for _, tt := range tests {
+		counter := metrics.GraphQLOperations.WithLabelValues(tt.name, tt.kind)
+		before := testutil.ToFloat64(counter)
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(tt.payload))
+		req.Header.Set("Content-Type", "application/json")
+		eng.ServeHTTP(httptest.NewRecorder(), req)
+		if got := testutil.ToFloat64(counter) - before; got != 1 {
+			t.Errorf("%s: counted %v times as %s/%s, want once", tt.payload, got, tt.name, tt.kind)
+		}
+		if body != tt.payload {
+			t.Errorf("handler body = %q, want %q", body, tt.payload)
+		}
+	}
+}
+
+func TestGraphQLMetricsBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	eng := gin.New()
+	called := false
+	eng.POST("/graphql", GraphQLMetrics(testSchema(t)), func(c *gin.Context) { called = true })
+
+	payload := `{"query":"{ liquors }","variables":{"pad":"` + strings.Repeat("x", maxGraphQLBody) + `"}}`
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	eng.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge || called {
+		t.Fatalf("status = %d, handler called %v, want 413 before the handler", rec.Code, called)
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"github.com/Cococtel/Cococtel_Gagateway/internal/apikeys"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/defines"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/ratelimit"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+	"github.com/gin-gonic/gin"
+	"math"
+	"strconv"
+)
+
+// RateLimitClient charges each request to the bucket of its API key and IP.
+// It runs before the auth middlewares so a flood of bad tokens is throttled
+// before it reaches the token verification.
+func RateLimitClient(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := ratelimit.Key(rateLimitClient(c), "ip:"+c.ClientIP())
+		take(c, limiter, ratelimit.Client, key)
+	}
+}
+
+// RateLimit charges each request to the caller's bucket for class. It must
+// run after RequireAuth or OptionalAuth so logged users get their own budget.
+func RateLimit(limiter *ratelimit.Limiter, class ratelimit.Class) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var userID string
+		if id, ok := CurrentIdentity(c); ok {
+			userID = id.UserID
+		}
+		key := ratelimit.Key(rateLimitClient(c), userID)
+		c.Request = c.Request.WithContext(ratelimit.NewContext(c.Request.Context(), key))
+		take(c, limiter, class, key)
+	}
+}
+
+// rateLimitClient names the API key of the request.
+func rateLimitClient(c *gin.Context) string {
+	// Con la api key registrada se usa su nombre, así rotar el secreto
+	// no reinicia el presupuesto.
+	if apiKey, ok := apikeys.FromContext(c.Request.Context()); ok {
+		return apiKey.Name
+	}
+	return c.GetHeader("x-api-key")
+}
+
+func take(c *gin.Context, limiter *ratelimit.Limiter, class ratelimit.Class, key string) {
+	result, applied := limiter.Take(c.Request.Context(), class, key)
+	if !applied {
+		c.Next()
+		return
+	}
+	setRateLimitHeaders(c, result)
+	if !result.Allowed {
+		utils.ApiErrorResponse(c, utils.NewTooManyRequestsError(defines.RateLimited, result.RetryAfter))
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+func setRateLimitHeaders(c *gin.Context, result ratelimit.Result) {
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.Reset.Seconds()))))
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitClientRunsBeforeAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth := &fakeAuth{}
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), map[ratelimit.Class]ratelimit.Limit{
+		ratelimit.Client: {Requests: 2, Per: time.Minute},
+	})
+	eng := gin.New()
+	eng.GET("/posts", RateLimitClient(limiter), RequireAuth(auth), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	steps := []struct {
+		ip   string
+		want int
+	}{
+		{"10.0.0.1", http.StatusUnauthorized},
+		{"10.0.0.1", http.StatusUnauthorized},
+		{"10.0.0.1", http.StatusTooManyRequests},
+		{"10.0.0.2", http.StatusUnauthorized},
+	}
+	for i, step := range steps {
+		req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+		req.RemoteAddr = step.ip + ":1234"
+		req.Header.Set("x-api-key", "app")
+		req.Header.Set("x-auth-token", "forged")
+		rec := httptest.NewRecorder()
+		eng.ServeHTTP(rec, req)
+		if rec.Code != step.want {
+			t.Fatalf("request %d from %s: status = %d, want %d", i+1, step.ip, rec.Code, step.want)
+		}
+	}
+	if auth.calls != 3 {
+		t.Fatalf("Authenticate() called %d times, want 3", auth.calls)
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/logging"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/requestid"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestIDRoundTrip(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(logging.New(&logs, "info"))
+
+	eng := gin.New()
+	eng.Use(RequestID(), AccessLog())
+	eng.GET("/liquors/:id", func(c *gin.Context) {
+		utils.ApiErrorResponse(c, utils.NewApiError(errors.New("liquor not found"), http.StatusNotFound))
+	})
+
+	tests := []struct {
+		name string
+		sent string
+		// keep means the id sent by the client is reused as is.
+		keep bool
+	}{
+		{"client id", "abc-123", true},
+		{"no id", "", false},
+		{"unsafe id", "<script>", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/liquors/l1", nil)
+			if tt.sent != "" {
+				req.Header.Set(requestid.Header, tt.sent)
+			}
+			rec := httptest.NewRecorder()
+			eng.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(requestid.Header)
+			if !requestid.Valid(id) || (id == tt.sent) != tt.keep {
+				t.Fatalf("response %s = %q for %q sent", requestid.Header, id, tt.sent)
+			}
+			var body struct {
+				Error struct {
+					RequestID string `json:"request_id"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Error.RequestID != id {
+				t.Fatalf("error body request_id = %q, want %q", body.Error.RequestID, id)
+			}
+			var line struct {
+				RequestID string `json:"request_id"`
+				Status    int    `json:"status"`
+			}
+			if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+				t.Fatalf("access log %q: %v", logs.String(), err)
+			}
+			if line.RequestID != id || line.Status != http.StatusNotFound {
+				t.Fatalf("access log = %+v, want request_id %q and status 404", line, id)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package policy
+
+import (
+	"errors"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+	"net/http"
+	"strings"
+)
+
+// Valores de SuccessfulLogin.AccountType que entiende el gateway.
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+var (
+	ErrUnauthenticated = errors.New("authentication required")
+	ErrForbidden       = errors.New("you are not allowed to perform this action")
+)
+
+// Rule says who may perform an operation: any of Roles, and also the owner
+// of the resource when Owner is set. A rule with neither only requires login.
+type Rule struct {
+	Name  string
+	Roles []string
+	Owner bool
+}
+
+var (
+	Authenticated = Rule{Name: "authenticated"}
+	Admin         = Rule{Name: "admin", Roles: []string{RoleAdmin}}
+	OwnerOrAdmin  = Rule{Name: "owner_or_admin", Roles: []string{RoleAdmin}, Owner: true}
+)
+
+// Reglas por operación, compartidas por el router y los resolvers de GraphQL.
+var (
+	LiquorWrite  = Admin
+	RecipeCreate = Authenticated
+	RecipeEdit   = OwnerOrAdmin
+	PostCreate   = Authenticated
+	PostEdit     = OwnerOrAdmin
+	PostInteract = Authenticated
+	// CommentDelete tiene como dueño al autor del comentario, no al del post.
+	CommentDelete = OwnerOrAdmin
+)
+
+func (r Rule) NeedsOwner() bool {
+	return r.Owner
+}
+
+// Allows reports whether id satisfies the rule for a resource owned by owner.
+func (r Rule) Allows(id *entities.Identity, owner string) bool {
+	if id == nil || id.UserID == "" {
+		return false
+	}
+	if len(r.Roles) == 0 && !r.Owner {
+		return true
+	}
+	if HasRole(id, r.Roles...) {
+		return true
+	}
+	return r.Owner && owner != "" && owner == id.UserID
+}
+
+// Authorize returns a 401 for anonymous callers and a 403 when the rule is not met.
+func Authorize(id *entities.Identity, rule Rule, owner string) utils.ApiError {
+	if id == nil || id.UserID == "" {
+		return utils.NewApiError(ErrUnauthenticated, http.StatusUnauthorized)
+	}
+	if !rule.Allows(id, owner) {
+		return utils.NewApiError(ErrForbidden, http.StatusForbidden)
+	}
+	return nil
+}
+
+func HasRole(id *entities.Identity, roles ...string) bool {
+	if id == nil {
+		return false
+	}
+	for _, role := range roles {
+		if strings.EqualFold(id.AccountType, role) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package policy
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+)
+
+func TestAuthorize(t *testing.T) {
+	admin := &entities.Identity{UserID: "a1", AccountType: "Admin"}
+	user := &entities.Identity{UserID: "u1", AccountType: RoleUser}
+
+	tests := []struct {
+		name   string
+		id     *entities.Identity
+		rule   Rule
+		owner  string
+		status int
+	}{
+		{"anonymous", nil, Authenticated, "", http.StatusUnauthorized},
+		{"identity without user", &entities.Identity{AccountType: RoleAdmin}, Admin, "", http.StatusUnauthorized},
+		{"logged in", user, Authenticated, "", 0},
+		{"user on an admin rule", user, Admin, "", http.StatusForbidden},
+		{"admin in any case", admin, Admin, "", 0},
+		{"owner", user, OwnerOrAdmin, "u1", 0},
+		{"not the owner", user, OwnerOrAdmin, "u2", http.StatusForbidden},
+		{"resource without owner", user, OwnerOrAdmin, "", http.StatusForbidden},
+		{"admin on someone else's resource", admin, OwnerOrAdmin, "u2", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := Authorize(tt.id, tt.rule, tt.owner)
+			status := 0
+			if apiErr != nil {
+				status = apiErr.Status()
+			}
+			if status != tt.status {
+				t.Fatalf("Authorize() status = %d, want %d", status, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Por encima de este tamaño se descartan los buckets que ya se rellenaron.
+const memorySweepSize = 4096
+
+type (
+	// MemoryStore is an in-process token bucket store.
+	MemoryStore struct {
+		now func() time.Time
+
+		mu      sync.Mutex
+		buckets map[string]*bucket
+	}
+
+	bucket struct {
+		tokens float64
+		last   time.Time
+		per    time.Duration
+	}
+)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	now := s.now()
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Per.Seconds()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		if len(s.buckets) >= memorySweepSize {
+			s.sweep(now)
+		}
+		b = &bucket{tokens: capacity, last: now}
+		s.buckets[key] = b
+	}
+	b.per = limit.Per
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	result := Result{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((capacity - b.tokens) / rate)
+	return result, nil
+}
+
+// SweepEvery drops idle buckets every interval until ctx is done, so keys
+// that stop sending requests don't stay in memory.
+func (s *MemoryStore) SweepEvery(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			s.mu.Lock()
+			s.sweep(s.now())
+			s.mu.Unlock()
+		}
+	}()
+}
+
+// sweep drops buckets idle long enough to be full again; recreating them
+// later gives the same result.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.last) >= b.per {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
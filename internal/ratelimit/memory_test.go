@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreRefill(t *testing.T) {
+	limit := Limit{Requests: 2, Per: 10 * time.Second}
+	steps := []struct {
+		name          string
+		advance       time.Duration
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     time.Duration
+	}{
+		{"first request", 0, true, 1, 0},
+		{"burst", 0, true, 0, 0},
+		{"bucket empty", 0, false, 0, 5 * time.Second},
+		{"half a token later", 2500 * time.Millisecond, false, 0, 2500 * time.Millisecond},
+		{"one token refilled", 2500 * time.Millisecond, true, 0, 0},
+		{"long idle refills up to the limit only", time.Hour, true, 1, 0},
+	}
+
+	now := time.Unix(0, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		result, err := store.Take(context.Background(), "k", limit)
+		if err != nil {
+			t.Fatalf("%s: Take() error = %v", step.name, err)
+		}
+		if result.Allowed != step.wantAllowed || result.Remaining != step.wantRemaining || result.RetryAfter != step.wantRetry {
+			t.Fatalf("%s: Take() = %+v, want allowed %v, remaining %d, retry after %v",
+				step.name, result, step.wantAllowed, step.wantRemaining, step.wantRetry)
+		}
+	}
+}
+
+func TestMemoryStoreKeysAreIndependent(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Requests: 1, Per: time.Minute}
+	for _, key := range []string{"a", "b"} {
+		if result, _ := store.Take(context.Background(), key, limit); !result.Allowed {
+			t.Fatalf("Take(%q) not allowed on a fresh bucket", key)
+		}
+	}
+	if result, _ := store.Take(context.Background(), "a", limit); result.Allowed {
+		t.Fatal("Take(\"a\") allowed past its limit")
+	}
+}
+
+func TestMemoryStoreSweepDropsIdleBuckets(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	store.Take(context.Background(), "idle", Limit{Requests: 1, Per: time.Minute})
+	store.Take(context.Background(), "busy", Limit{Requests: 1, Per: time.Hour})
+
+	now = now.Add(time.Minute)
+	store.sweep(now)
+	if _, ok := store.buckets["idle"]; ok {
+		t.Fatal("sweep() kept a bucket idle for its whole period")
+	}
+	if _, ok := store.buckets["busy"]; !ok {
+		t.Fatal("sweep() dropped a bucket that is still refilling")
+	}
+}
+
+func TestMemoryStoreSweepEvery(t *testing.T) {
+	store := NewMemoryStore()
+	store.Take(context.Background(), "k", Limit{Requests: 1, Per: time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store.SweepEvery(ctx, time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		store.mu.Lock()
+		n := len(store.buckets)
+		store.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("SweepEvery() never dropped the idle bucket")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLimiterWithoutLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		limiter *Limiter
+		class   Class
+	}{
+		{"nil limiter", nil, Cheap},
+		{"class without limit", NewLimiter(NewMemoryStore(), map[Class]Limit{Cheap: {Requests: 1, Per: time.Second}}), Expensive},
+		{"zero limit", NewLimiter(NewMemoryStore(), map[Class]Limit{Cheap: {}}), Cheap},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, limited := tt.limiter.Take(context.Background(), tt.class, "k"); limited {
+				t.Fatal("Take() applied a limit")
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Cheap cubre las lecturas del catálogo y el resto de rutas livianas.
+	Cheap Class = "cheap"
+	// Expensive cubre IA, OCR y scrapping, que cuestan mucho más por request.
+	Expensive Class = "expensive"
+	// Client cubre todo lo que llega de una api key e IP, antes de verificar
+	// el token del usuario.
+	Client Class = "client"
+)
+
+type (
+	Class string
+
+	// Limit allows Requests per Per, with bursts of up to Requests.
+	Limit struct {
+		Requests int
+		Per      time.Duration
+	}
+
+	Result struct {
+		Allowed    bool
+		Limit      int
+		Remaining  int
+		Reset      time.Duration
+		RetryAfter time.Duration
+	}
+
+	// Store keeps the token buckets. MemoryStore works for a single instance;
+	// a shared store (e.g. Redis) is needed when running several replicas.
+	Store interface {
+		Take(ctx context.Context, key string, limit Limit) (Result, error)
+	}
+
+	Limiter struct {
+		store  Store
+		limits map[Class]Limit
+	}
+
+	contextKey struct{}
+)
+
+func NewLimiter(store Store, limits map[Class]Limit) *Limiter {
+	return &Limiter{store: store, limits: limits}
+}
+
+// Take consumes one request of class for key and reports whether a limit was
+// applied. A nil limiter, a class without a limit or a failing store let the
+// request through.
+func (l *Limiter) Take(ctx context.Context, class Class, key string) (Result, bool) {
+	if l == nil {
+		return Result{}, false
+	}
+	limit, ok := l.limits[class]
+	if !ok || limit.Requests <= 0 || limit.Per <= 0 {
+		return Result{}, false
+	}
+	result, err := l.store.Take(ctx, string(class)+":"+key, limit)
+	if err != nil {
+		return Result{}, false
+	}
+	return result, true
+}
+
+// Key identifies the caller by API key and, when logged in, by user id.
+// The client is hashed so a raw API key is never stored as is.
+func Key(client, userID string) string {
+	sum := sha256.Sum256([]byte(client))
+	key := hex.EncodeToString(sum[:8])
+	if userID != "" {
+		key += ":" + userID
+	}
+	return key
+}
+
+// NewContext stores the caller's key so GraphQL resolvers can charge
+// expensive fields to the same bucket the middleware used.
+func NewContext(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+func KeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(contextKey{}).(string)
+	return key, ok
+}
+
+// ParseLimit reads limits written as "<requests>/<period>", e.g. "20/1m".
+func ParseLimit(value string) (Limit, error) {
+	requests, period, found := strings.Cut(strings.TrimSpace(value), "/")
+	if !found {
+		return Limit{}, fmt.Errorf("invalid rate limit %q", value)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n < 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q", value)
+	}
+	per, err := time.ParseDuration(period)
+	if err != nil || per <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q", value)
+	}
+	return Limit{Requests: n, Per: per}, nil
+}
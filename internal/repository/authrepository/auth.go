@@ -2,112 +2,91 @@ package authrepository
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/dtos"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
-	"io/ioutil"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/upstream"
+	"io"
 	"net/http"
-	"os"
 )
 
 type (
 	IAuth interface {
-		Verify(token string) error
-		Register(user dtos.Register) (*entities.User, error)
-		Login(credentails dtos.Login) (*entities.SuccessfulLogin, error)
-		GetUser(id string, token string) (*entities.User, error)
-		EditUser(user dtos.User, token string) error
+		Verify(ctx context.Context, token string) error
+		Register(ctx context.Context, user dtos.Register) (*entities.User, error)
+		Login(ctx context.Context, credentails dtos.Login) (*entities.SuccessfulLogin, error)
+		GetUser(ctx context.Context, id string, token string) (*entities.User, error)
+		EditUser(ctx context.Context, user dtos.User, token string) error
+		FetchJWKS(ctx context.Context, path string) ([]byte, error)
+	}
+	authRepository struct {
+		client *upstream.Client
 	}
-	authRepository struct{}
 )
 
-var ms_authorization_endpoint string
-
-func NewAuthRepository() IAuth {
-	ms_authorization_endpoint = os.Getenv("MS_AUTH_DOMAIN")
-	return &authRepository{}
+func NewAuthRepository(client *upstream.Client) IAuth {
+	return &authRepository{client: client}
 }
 
-func (r *authRepository) Verify(token string) error {
-	url := fmt.Sprintf("%s/v1/verify", ms_authorization_endpoint)
-	req, err := http.NewRequest(http.MethodPost, url, nil)
+func (r *authRepository) Verify(ctx context.Context, token string) error {
+	req, err := r.client.NewRequest(ctx, http.MethodPost, r.client.URL("/v1/verify"), nil)
 	if err != nil {
 		return err
 	}
 	req.Header.Set("x-auth-token", token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := r.client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return errors.New("unauthorized")
-	}
-
-	return nil
+	return r.client.Check(resp)
 }
 
-func (r *authRepository) Register(user dtos.Register) (*entities.User, error) {
-	url := fmt.Sprintf("%s/register", ms_authorization_endpoint)
+func (r *authRepository) Register(ctx context.Context, user dtos.Register) (*entities.User, error) {
 	body, _ := json.Marshal(user)
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(body))
+	resp, err := r.client.Post(ctx, r.client.URL("/register"), "application/json", bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	responseBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
 	var userResp entities.UserResponse
-	if err := json.Unmarshal(responseBody, &userResp); err != nil {
+	if err := r.client.Decode(resp, &userResp); err != nil {
 		return nil, err
 	}
 
 	return &userResp.Data, nil
 }
 
-func (r *authRepository) Login(credentails dtos.Login) (*entities.SuccessfulLogin, error) {
-	url := fmt.Sprintf("%s/login", ms_authorization_endpoint)
+func (r *authRepository) Login(ctx context.Context, credentails dtos.Login) (*entities.SuccessfulLogin, error) {
 	body, _ := json.Marshal(credentails)
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(body))
+	resp, err := r.client.Post(ctx, r.client.URL("/login"), "application/json", bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	responseBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
 	var loginResp entities.LoginResponse
-	if err := json.Unmarshal(responseBody, &loginResp); err != nil {
+	if err := r.client.Decode(resp, &loginResp); err != nil {
 		return nil, err
 	}
 
 	return &loginResp.Data, nil
 }
 
-func (r *authRepository) GetUser(id string, token string) (*entities.User, error) {
-	url := fmt.Sprintf("%s/v1/profile/%s", ms_authorization_endpoint, id)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+func (r *authRepository) GetUser(ctx context.Context, id string, token string) (*entities.User, error) {
+	req, err := r.client.NewRequest(ctx, http.MethodGet, r.client.URL("/v1/profile/%s", id), nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("x-auth-token", token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := r.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -116,36 +95,43 @@ func (r *authRepository) GetUser(id string, token string) (*entities.User, error
 	var response struct {
 		Data entities.User `json:"data"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+	if err := r.client.Decode(resp, &response); err != nil {
 		return nil, err
 	}
 
 	if response.Data.Name == "" {
-		return nil, errors.New("user not found")
+		return nil, r.client.NotFound("user not found")
 	}
 
 	return &response.Data, nil
 }
 
-func (r *authRepository) EditUser(user dtos.User, token string) error {
-	url := fmt.Sprintf("%s/v1/profile", ms_authorization_endpoint)
+func (r *authRepository) EditUser(ctx context.Context, user dtos.User, token string) error {
 	body, _ := json.Marshal(user)
-	req, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(body))
+	req, err := r.client.NewRequest(ctx, http.MethodPut, r.client.URL("/v1/profile"), bytes.NewBuffer(body))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("x-auth-token", token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := r.client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return errors.New(resp.Status)
+	return r.client.Check(resp)
+}
+
+func (r *authRepository) FetchJWKS(ctx context.Context, path string) ([]byte, error) {
+	resp, err := r.client.Get(ctx, r.client.URL(path))
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	return nil
+	if err := r.client.Check(resp); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(resp.Body)
 }
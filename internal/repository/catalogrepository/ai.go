@@ -2,43 +2,45 @@ package catalogrepository
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/upstream"
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
-	"os"
+	"net/url"
 )
 
 type (
 	IAI interface {
-		ProcessStrings(input []string) (string, error)
-		CreateRecipe(liquor string) (*entities.AIRecipe, error)
-		ExtractTextFromImage(imageBytes []byte) ([]string, error)
+		ProcessStrings(ctx context.Context, input []string) (string, error)
+		CreateRecipe(ctx context.Context, liquor string) (*entities.AIRecipe, error)
+		ExtractTextFromImage(ctx context.Context, imageBytes []byte) ([]string, error)
+	}
+	aiRepository struct {
+		aiClient               *upstream.Client
+		imageRecognitionClient *upstream.Client
 	}
-	aiRepository struct{}
 )
 
-var ms_ai_endpoint string
-var ms_ai_image_recognition string
-
-func NewAIRepository() IAI {
-	ms_ai_endpoint = os.Getenv("MS_AI_DOMAIN")
-	ms_ai_image_recognition = os.Getenv("MS_IMAGE_RECOGNITION_DOMAIN")
-	return &aiRepository{}
+func NewAIRepository(aiClient, imageRecognitionClient *upstream.Client) IAI {
+	return &aiRepository{aiClient: aiClient, imageRecognitionClient: imageRecognitionClient}
 }
 
-func (ir *aiRepository) ProcessStrings(input []string) (string, error) {
-	url := fmt.Sprintf("%s/DeduceLiquorName", ms_ai_endpoint)
+func (ir *aiRepository) ProcessStrings(ctx context.Context, input []string) (string, error) {
 	body, _ := json.Marshal(input)
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(body))
+	resp, err := ir.aiClient.Post(ctx, ir.aiClient.URL("/DeduceLiquorName"), "application/json", bytes.NewBuffer(body))
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
+	if err := ir.aiClient.Check(resp); err != nil {
+		return "", err
+	}
+
 	resultBytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return "", err
@@ -47,24 +49,22 @@ func (ir *aiRepository) ProcessStrings(input []string) (string, error) {
 	return string(resultBytes), nil
 }
 
-func (ir *aiRepository) CreateRecipe(liquor string) (*entities.AIRecipe, error) {
-	url := fmt.Sprintf("%s/CreateRecipe?liquor=%s", ms_ai_endpoint, liquor)
-
-	resp, err := http.Post(url, "application/json", nil)
+func (ir *aiRepository) CreateRecipe(ctx context.Context, liquor string) (*entities.AIRecipe, error) {
+	resp, err := ir.aiClient.Post(ctx, ir.aiClient.URL("/CreateRecipe")+"?liquor="+url.QueryEscape(liquor), "application/json", nil)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var recipe entities.AIRecipe
-	if err := json.NewDecoder(resp.Body).Decode(&recipe); err != nil {
+	if err := ir.aiClient.Decode(resp, &recipe); err != nil {
 		return nil, err
 	}
 
 	return &recipe, nil
 }
 
-func (ir *aiRepository) ExtractTextFromImage(imageBytes []byte) ([]string, error) {
+func (ir *aiRepository) ExtractTextFromImage(ctx context.Context, imageBytes []byte) ([]string, error) {
 	// Crear un buffer y un multipart writer para construir la solicitud form-data.
 	var buf bytes.Buffer
 	writer := multipart.NewWriter(&buf)
@@ -84,26 +84,21 @@ func (ir *aiRepository) ExtractTextFromImage(imageBytes []byte) ([]string, error
 	}
 
 	// Construir la solicitud HTTP con el body del multipart.
-	req, err := http.NewRequest(http.MethodPost, ms_ai_image_recognition, &buf)
+	req, err := ir.imageRecognitionClient.NewRequest(ctx, http.MethodPost, ir.imageRecognitionClient.URL(""), &buf)
 	if err != nil {
 		return nil, err
 	}
 	// Establecer el Content-Type con el boundary generado.
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := ir.imageRecognitionClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("status error: %v", resp.Status)
-	}
-
 	var texts []string
-	if err := json.NewDecoder(resp.Body).Decode(&texts); err != nil {
+	if err := ir.imageRecognitionClient.Decode(resp, &texts); err != nil {
 		return nil, err
 	}
 	return texts, nil
@@ -0,0 +1,106 @@
+package catalogrepository
+
+import (
+	"context"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/cache"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/dtos"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+	"net/url"
+)
+
+const (
+	liquorsKey = "catalog:liquors"
+	recipesKey = "catalog:recipes"
+)
+
+type (
+	CacheTTLs struct {
+		Liquors cache.TTL
+		Recipes cache.TTL
+	}
+
+	// cachedCatalog serves catalog reads from the cache and drops the
+	// affected entries on every write that goes through the gateway.
+	cachedCatalog struct {
+		next  ICatalog
+		cache *cache.Cache
+		ttls  CacheTTLs
+	}
+)
+
+func NewCachedCatalog(next ICatalog, c *cache.Cache, ttls CacheTTLs) ICatalog {
+	return &cachedCatalog{next: next, cache: c, ttls: ttls}
+}
+
+func liquorKey(id string) string { return liquorsKey + ":" + id }
+func recipeKey(id string) string { return recipesKey + ":" + id }
+
+func (cc *cachedCatalog) FetchLiquors(ctx context.Context) ([]entities.Liquor, error) {
+	return cache.Fetch(ctx, cc.cache, liquorsKey, cc.ttls.Liquors, cc.next.FetchLiquors)
+}
+
+// QueryLiquors is not cached: filtered and paginated listings are keyed by
+// too many combinations and invalidating them all on every write is not
+// worth it.
+func (cc *cachedCatalog) QueryLiquors(ctx context.Context, values url.Values) ([]entities.Liquor, int, error) {
+	if querier, ok := cc.next.(ListQuerier); ok && len(values) > 0 {
+		return querier.QueryLiquors(ctx, values)
+	}
+	items, err := cc.FetchLiquors(ctx)
+	return items, -1, err
+}
+
+func (cc *cachedCatalog) FetchLiquorByID(ctx context.Context, id string) (*entities.Liquor, error) {
+	return cache.Fetch(ctx, cc.cache, liquorKey(id), cc.ttls.Liquors, func(ctx context.Context) (*entities.Liquor, error) {
+		return cc.next.FetchLiquorByID(ctx, id)
+	})
+}
+
+func (cc *cachedCatalog) CreateLiquor(ctx context.Context, liquor dtos.Liquor) (*entities.Liquor, error) {
+	defer cc.cache.Invalidate(ctx, liquorsKey)
+	return cc.next.CreateLiquor(ctx, liquor)
+}
+
+func (cc *cachedCatalog) UpdateLiquor(ctx context.Context, id string, updates map[string]interface{}) (*entities.Liquor, error) {
+	// Se invalida también si falla: el upstream pudo aplicar el cambio igual.
+	defer cc.cache.Invalidate(ctx, liquorsKey, liquorKey(id))
+	return cc.next.UpdateLiquor(ctx, id, updates)
+}
+
+func (cc *cachedCatalog) DeleteLiquor(ctx context.Context, id string) error {
+	defer cc.cache.Invalidate(ctx, liquorsKey, liquorKey(id))
+	return cc.next.DeleteLiquor(ctx, id)
+}
+
+func (cc *cachedCatalog) FetchRecipes(ctx context.Context) ([]entities.Recipe, error) {
+	return cache.Fetch(ctx, cc.cache, recipesKey, cc.ttls.Recipes, cc.next.FetchRecipes)
+}
+
+func (cc *cachedCatalog) QueryRecipes(ctx context.Context, values url.Values) ([]entities.Recipe, int, error) {
+	if querier, ok := cc.next.(ListQuerier); ok && len(values) > 0 {
+		return querier.QueryRecipes(ctx, values)
+	}
+	items, err := cc.FetchRecipes(ctx)
+	return items, -1, err
+}
+
+func (cc *cachedCatalog) FetchRecipeByID(ctx context.Context, id string) (*entities.Recipe, error) {
+	return cache.Fetch(ctx, cc.cache, recipeKey(id), cc.ttls.Recipes, func(ctx context.Context) (*entities.Recipe, error) {
+		return cc.next.FetchRecipeByID(ctx, id)
+	})
+}
+
+func (cc *cachedCatalog) CreateRecipe(ctx context.Context, recipe dtos.Recipe) (*entities.Recipe, error) {
+	defer cc.cache.Invalidate(ctx, recipesKey)
+	return cc.next.CreateRecipe(ctx, recipe)
+}
+
+func (cc *cachedCatalog) UpdateRecipe(ctx context.Context, id string, updates map[string]interface{}) (*entities.Recipe, error) {
+	defer cc.cache.Invalidate(ctx, recipesKey, recipeKey(id))
+	return cc.next.UpdateRecipe(ctx, id, updates)
+}
+
+func (cc *cachedCatalog) DeleteRecipe(ctx context.Context, id string) error {
+	defer cc.cache.Invalidate(ctx, recipesKey, recipeKey(id))
+	return cc.next.DeleteRecipe(ctx, id)
+}
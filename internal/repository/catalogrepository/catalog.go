@@ -2,160 +2,178 @@ package catalogrepository
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/dtos"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/upstream"
 	"net/http"
-	"os"
+	"net/url"
+	"strconv"
 )
 
 type (
 	ICatalog interface {
-		FetchLiquors() ([]entities.Liquor, error)
-		FetchLiquorByID(id string) (*entities.Liquor, error)
-		CreateLiquor(liquor dtos.Liquor) (*entities.Liquor, error)
-		UpdateLiquor(id string, updates map[string]interface{}) (*entities.Liquor, error)
-		DeleteLiquor(id string) error
-		FetchRecipes() ([]entities.Recipe, error)
-		FetchRecipeByID(id string) (*entities.Recipe, error)
-		CreateRecipe(recipe dtos.Recipe) (*entities.Recipe, error)
-		UpdateRecipe(id string, updates map[string]interface{}) (*entities.Recipe, error)
-		DeleteRecipe(id string) error
-	}
-	catalogRepository struct{}
+		FetchLiquors(ctx context.Context) ([]entities.Liquor, error)
+		FetchLiquorByID(ctx context.Context, id string) (*entities.Liquor, error)
+		CreateLiquor(ctx context.Context, liquor dtos.Liquor) (*entities.Liquor, error)
+		UpdateLiquor(ctx context.Context, id string, updates map[string]interface{}) (*entities.Liquor, error)
+		DeleteLiquor(ctx context.Context, id string) error
+		FetchRecipes(ctx context.Context) ([]entities.Recipe, error)
+		FetchRecipeByID(ctx context.Context, id string) (*entities.Recipe, error)
+		CreateRecipe(ctx context.Context, recipe dtos.Recipe) (*entities.Recipe, error)
+		UpdateRecipe(ctx context.Context, id string, updates map[string]interface{}) (*entities.Recipe, error)
+		DeleteRecipe(ctx context.Context, id string) error
+	}
+
+	// ListQuerier is for a catalog service that filters, sorts and
+	// paginates the listings itself, taking the values built by
+	// listing.Query.PageValues. total comes from X-Total-Count and is -1
+	// when the service did not send it, meaning it returned every match.
+	ListQuerier interface {
+		QueryLiquors(ctx context.Context, values url.Values) (liquors []entities.Liquor, total int, err error)
+		QueryRecipes(ctx context.Context, values url.Values) (recipes []entities.Recipe, total int, err error)
+	}
+
+	catalogRepository struct {
+		client *upstream.Client
+	}
 )
 
-var ms_catalog_endpoint string
+func NewCatalogRepository(client *upstream.Client) ICatalog {
+	return &catalogRepository{client: client}
+}
 
-func NewCatalogRepository() ICatalog {
-	ms_catalog_endpoint = os.Getenv("MS_CATALOG_DOMAIN")
-	return &catalogRepository{}
+func (cr *catalogRepository) FetchLiquors(ctx context.Context) ([]entities.Liquor, error) {
+	liquors, _, err := cr.QueryLiquors(ctx, nil)
+	return liquors, err
 }
 
-func (cr *catalogRepository) FetchLiquors() ([]entities.Liquor, error) {
-	resp, err := http.Get(ms_catalog_endpoint + "/liquors")
+func (cr *catalogRepository) QueryLiquors(ctx context.Context, values url.Values) ([]entities.Liquor, int, error) {
+	resp, err := cr.client.Get(ctx, cr.client.URL("/liquors")+query(values))
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 
 	var liquors []entities.Liquor
-	if err := json.NewDecoder(resp.Body).Decode(&liquors); err != nil {
-		return nil, err
-	}
-
-	if len(liquors) == 0 {
-		return nil, errors.New("liquors not found")
+	if err := cr.client.Decode(resp, &liquors); err != nil {
+		return nil, 0, err
 	}
 
-	return liquors, nil
+	return liquors, totalCount(resp), nil
 }
 
-func (cr *catalogRepository) FetchLiquorByID(id string) (*entities.Liquor, error) {
-	url := fmt.Sprintf("%s/liquors/%s", ms_catalog_endpoint, id)
-	resp, err := http.Get(url)
+func (cr *catalogRepository) FetchLiquorByID(ctx context.Context, id string) (*entities.Liquor, error) {
+	resp, err := cr.client.Get(ctx, cr.client.URL("/liquors/%s", id))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var liquor entities.Liquor
-	if err := json.NewDecoder(resp.Body).Decode(&liquor); err != nil {
+	if err := cr.client.Decode(resp, &liquor); err != nil {
 		return nil, err
 	}
 
 	if liquor.ID == "" {
-		return nil, errors.New("liquor not found")
+		return nil, cr.client.NotFound("liquor not found")
 	}
 
 	return &liquor, nil
 }
 
-func (cr *catalogRepository) CreateLiquor(liquor dtos.Liquor) (*entities.Liquor, error) {
+func (cr *catalogRepository) CreateLiquor(ctx context.Context, liquor dtos.Liquor) (*entities.Liquor, error) {
 	body, _ := json.Marshal(liquor)
-	resp, err := http.Post(ms_catalog_endpoint+"/liquors", "application/json", bytes.NewBuffer(body))
+	resp, err := cr.client.Post(ctx, cr.client.URL("/liquors"), "application/json", bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var newLiquor entities.Liquor
-	if err := json.NewDecoder(resp.Body).Decode(&newLiquor); err != nil {
+	if err := cr.client.Decode(resp, &newLiquor); err != nil {
 		return nil, err
 	}
 
 	return &newLiquor, nil
 }
 
-func (cr *catalogRepository) UpdateLiquor(id string, updates map[string]interface{}) (*entities.Liquor, error) {
-	url := fmt.Sprintf("%s/liquors/%s", ms_catalog_endpoint, id)
+func (cr *catalogRepository) UpdateLiquor(ctx context.Context, id string, updates map[string]interface{}) (*entities.Liquor, error) {
 	body, _ := json.Marshal(updates)
 
-	req, _ := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(body))
+	req, err := cr.client.NewRequest(ctx, http.MethodPut, cr.client.URL("/liquors/%s", id), bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := cr.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var updatedLiquor entities.Liquor
-	if err := json.NewDecoder(resp.Body).Decode(&updatedLiquor); err != nil {
+	if err := cr.client.Decode(resp, &updatedLiquor); err != nil {
 		return nil, err
 	}
 
 	return &updatedLiquor, nil
 }
 
-func (cr *catalogRepository) DeleteLiquor(id string) error {
-	url := fmt.Sprintf("%s/liquors/%s", ms_catalog_endpoint, id)
-
-	req, _ := http.NewRequest(http.MethodDelete, url, nil)
-	client := &http.Client{}
-	resp, err := client.Do(req)
+func (cr *catalogRepository) DeleteLiquor(ctx context.Context, id string) error {
+	req, err := cr.client.NewRequest(ctx, http.MethodDelete, cr.client.URL("/liquors/%s", id), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := cr.client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	return nil
+	return cr.client.Check(resp)
 }
 
-func (cr *catalogRepository) FetchRecipes() ([]entities.Recipe, error) {
-	resp, err := http.Get(ms_catalog_endpoint + "/recipes")
+func (cr *catalogRepository) FetchRecipes(ctx context.Context) ([]entities.Recipe, error) {
+	recipes, _, err := cr.QueryRecipes(ctx, nil)
+	return recipes, err
+}
+
+func (cr *catalogRepository) QueryRecipes(ctx context.Context, values url.Values) ([]entities.Recipe, int, error) {
+	resp, err := cr.client.Get(ctx, cr.client.URL("/recipes")+query(values))
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 
 	var recipes []entities.Recipe
-	if err := json.NewDecoder(resp.Body).Decode(&recipes); err != nil {
-		return nil, err
+	if err := cr.client.Decode(resp, &recipes); err != nil {
+		return nil, 0, err
 	}
 
 	for i := range recipes {
 		recipes[i].Rating, recipes[i].AverageRating = calculateAverageRating(recipes[i].Ratings)
 	}
 
-	return recipes, nil
+	return recipes, totalCount(resp), nil
 }
 
-func (cr *catalogRepository) FetchRecipeByID(id string) (*entities.Recipe, error) {
-	url := fmt.Sprintf("%s/recipes/%s", ms_catalog_endpoint, id)
-	resp, err := http.Get(url)
+func (cr *catalogRepository) FetchRecipeByID(ctx context.Context, id string) (*entities.Recipe, error) {
+	resp, err := cr.client.Get(ctx, cr.client.URL("/recipes/%s", id))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var recipe entities.Recipe
-	if err := json.NewDecoder(resp.Body).Decode(&recipe); err != nil {
+	if err := cr.client.Decode(resp, &recipe); err != nil {
 		return nil, err
 	}
+	if recipe.ID == "" {
+		return nil, cr.client.NotFound("recipe not found")
+	}
 
 	// Calcular rating y averageRating
 	recipe.Rating, recipe.AverageRating = calculateAverageRating(recipe.Ratings)
@@ -163,56 +181,73 @@ func (cr *catalogRepository) FetchRecipeByID(id string) (*entities.Recipe, error
 	return &recipe, nil
 }
 
-func (cr *catalogRepository) CreateRecipe(recipe dtos.Recipe) (*entities.Recipe, error) {
+func (cr *catalogRepository) CreateRecipe(ctx context.Context, recipe dtos.Recipe) (*entities.Recipe, error) {
 	body, _ := json.Marshal(recipe)
-	resp, err := http.Post(ms_catalog_endpoint+"/recipes", "application/json", bytes.NewBuffer(body))
+	resp, err := cr.client.Post(ctx, cr.client.URL("/recipes"), "application/json", bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var newRecipe entities.Recipe
-	if err := json.NewDecoder(resp.Body).Decode(&newRecipe); err != nil {
+	if err := cr.client.Decode(resp, &newRecipe); err != nil {
 		return nil, err
 	}
 
 	return &newRecipe, nil
 }
 
-func (cr *catalogRepository) UpdateRecipe(id string, updates map[string]interface{}) (*entities.Recipe, error) {
-	url := fmt.Sprintf("%s/recipes/%s", ms_catalog_endpoint, id)
+func (cr *catalogRepository) UpdateRecipe(ctx context.Context, id string, updates map[string]interface{}) (*entities.Recipe, error) {
 	body, _ := json.Marshal(updates)
 
-	req, _ := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(body))
+	req, err := cr.client.NewRequest(ctx, http.MethodPut, cr.client.URL("/recipes/%s", id), bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := cr.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var updatedRecipe entities.Recipe
-	if err := json.NewDecoder(resp.Body).Decode(&updatedRecipe); err != nil {
+	if err := cr.client.Decode(resp, &updatedRecipe); err != nil {
 		return nil, err
 	}
 
 	return &updatedRecipe, nil
 }
 
-func (cr *catalogRepository) DeleteRecipe(id string) error {
-	url := fmt.Sprintf("%s/recipes/%s", ms_catalog_endpoint, id)
-
-	req, _ := http.NewRequest(http.MethodDelete, url, nil)
-	client := &http.Client{}
-	resp, err := client.Do(req)
+func (cr *catalogRepository) DeleteRecipe(ctx context.Context, id string) error {
+	req, err := cr.client.NewRequest(ctx, http.MethodDelete, cr.client.URL("/recipes/%s", id), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := cr.client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	return nil
+	return cr.client.Check(resp)
+}
+
+func query(values url.Values) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return "?" + values.Encode()
+}
+
+// totalCount reads X-Total-Count, the number of matches of a paginated listing.
+func totalCount(resp *http.Response) int {
+	total, err := strconv.Atoi(resp.Header.Get("X-Total-Count"))
+	if err != nil || total < 0 {
+		return -1
+	}
+	return total
 }
 
 // 📌 Función para calcular rating y averageRating
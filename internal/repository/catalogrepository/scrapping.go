@@ -1,43 +1,37 @@
 package catalogrepository
 
 import (
-	"encoding/json"
-	"errors"
-	"fmt"
+	"context"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
-	"net/http"
-	"os"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/upstream"
 )
 
 type (
 	IScrapping interface {
-		GetProductByCode(code string) (*entities.Product, error)
+		GetProductByCode(ctx context.Context, code string) (*entities.Product, error)
+	}
+	scrappingRepository struct {
+		client *upstream.Client
 	}
-	scrappingRepository struct{}
 )
 
-var ms_scrapping_endpoint string
-
-func NewScrappingRepository() IScrapping {
-	ms_scrapping_endpoint = os.Getenv("MS_SCRAPPING_DOMAIN")
-	return &scrappingRepository{}
+func NewScrappingRepository(client *upstream.Client) IScrapping {
+	return &scrappingRepository{client: client}
 }
 
-func (sr *scrappingRepository) GetProductByCode(code string) (*entities.Product, error) {
-	url := fmt.Sprintf("%s/%s", ms_scrapping_endpoint, code)
-
-	resp, err := http.Get(url)
+func (sr *scrappingRepository) GetProductByCode(ctx context.Context, code string) (*entities.Product, error) {
+	resp, err := sr.client.Get(ctx, sr.client.URL("/%s", code))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var product entities.Product
-	if err := json.NewDecoder(resp.Body).Decode(&product); err != nil {
+	if err := sr.client.Decode(resp, &product); err != nil {
 		return nil, err
 	}
 	if product.Name == "" {
-		return nil, errors.New("product not found")
+		return nil, sr.client.NotFound("product not found")
 	}
 
 	return &product, nil
@@ -2,123 +2,134 @@ package postrepository
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/dtos"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/upstream"
 	"net/http"
-	"os"
 )
 
 type IPost interface {
-	FetchPosts() ([]entities.Post, error)
-	FetchPostByID(id string) (*entities.Post, error)
-	CreatePost(post dtos.Post) (*entities.Post, error)
-	UpdatePost(id string, updates map[string]interface{}) (*entities.Post, error)
-	DeletePost(id string) error
+	FetchPosts(ctx context.Context) ([]entities.Post, error)
+	FetchPostByID(ctx context.Context, id string) (*entities.Post, error)
+	CreatePost(ctx context.Context, post dtos.Post) (*entities.Post, error)
+	UpdatePost(ctx context.Context, id string, updates map[string]interface{}) (*entities.Post, error)
+	// FetchPostVersion is FetchPostByID plus the ETag sent by the posts
+	// service, empty when it sends none.
+	FetchPostVersion(ctx context.Context, id string) (*entities.Post, string, error)
+	// UpdatePostIfMatch sends the update with If-Match: etag, so it fails
+	// with a conflict when the post changed after it was read.
+	UpdatePostIfMatch(ctx context.Context, id, etag string, updates map[string]interface{}) (*entities.Post, error)
+	DeletePost(ctx context.Context, id string) error
 }
 
-type postRepository struct{}
-
-var ms_posts_endpoint string
+type postRepository struct {
+	client *upstream.Client
+}
 
-func NewCatalogRepository() IPost {
-	ms_posts_endpoint = os.Getenv("MS_CATALOG_DOMAIN")
-	return &postRepository{}
+func NewCatalogRepository(client *upstream.Client) IPost {
+	return &postRepository{client: client}
 }
 
 // FetchPosts obtiene todos los posts.
-func (r *postRepository) FetchPosts() ([]entities.Post, error) {
-	resp, err := http.Get(ms_posts_endpoint + "/posts")
+func (r *postRepository) FetchPosts(ctx context.Context) ([]entities.Post, error) {
+	resp, err := r.client.Get(ctx, r.client.URL("/posts"))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var posts []entities.Post
-	if err := json.NewDecoder(resp.Body).Decode(&posts); err != nil {
+	if err := r.client.Decode(resp, &posts); err != nil {
 		return nil, err
 	}
 	return posts, nil
 }
 
 // FetchPostByID obtiene un post por ID.
-func (r *postRepository) FetchPostByID(id string) (*entities.Post, error) {
-	url := fmt.Sprintf("%s/posts/%s", ms_posts_endpoint, id)
-	resp, err := http.Get(url)
+func (r *postRepository) FetchPostByID(ctx context.Context, id string) (*entities.Post, error) {
+	post, _, err := r.FetchPostVersion(ctx, id)
+	return post, err
+}
+
+func (r *postRepository) FetchPostVersion(ctx context.Context, id string) (*entities.Post, string, error) {
+	resp, err := r.client.Get(ctx, r.client.URL("/posts/%s", id))
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer resp.Body.Close()
 
 	var post entities.Post
-	if err := json.NewDecoder(resp.Body).Decode(&post); err != nil {
-		return nil, err
+	if err := r.client.Decode(resp, &post); err != nil {
+		return nil, "", err
 	}
 	if post.ID == "" {
-		return nil, errors.New("post not found")
+		return nil, "", r.client.NotFound("post not found")
 	}
-	return &post, nil
+	return &post, resp.Header.Get("ETag"), nil
 }
 
 // CreatePost crea un nuevo post.
-func (r *postRepository) CreatePost(post dtos.Post) (*entities.Post, error) {
+func (r *postRepository) CreatePost(ctx context.Context, post dtos.Post) (*entities.Post, error) {
 	body, err := json.Marshal(post)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http.Post(ms_posts_endpoint+"/posts", "application/json", bytes.NewBuffer(body))
+	resp, err := r.client.Post(ctx, r.client.URL("/posts"), "application/json", bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var newPost entities.Post
-	if err := json.NewDecoder(resp.Body).Decode(&newPost); err != nil {
+	if err := r.client.Decode(resp, &newPost); err != nil {
 		return nil, err
 	}
 	return &newPost, nil
 }
 
 // UpdatePost actualiza un post existente.
-func (r *postRepository) UpdatePost(id string, updates map[string]interface{}) (*entities.Post, error) {
-	url := fmt.Sprintf("%s/posts/%s", ms_posts_endpoint, id)
+func (r *postRepository) UpdatePost(ctx context.Context, id string, updates map[string]interface{}) (*entities.Post, error) {
+	return r.UpdatePostIfMatch(ctx, id, "", updates)
+}
+
+func (r *postRepository) UpdatePostIfMatch(ctx context.Context, id, etag string, updates map[string]interface{}) (*entities.Post, error) {
 	body, err := json.Marshal(updates)
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(body))
+	req, err := r.client.NewRequest(ctx, http.MethodPut, r.client.URL("/posts/%s", id), bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+	resp, err := r.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var updatedPost entities.Post
-	if err := json.NewDecoder(resp.Body).Decode(&updatedPost); err != nil {
+	if err := r.client.Decode(resp, &updatedPost); err != nil {
 		return nil, err
 	}
 	return &updatedPost, nil
 }
 
 // DeletePost elimina un post por ID.
-func (r *postRepository) DeletePost(id string) error {
-	url := fmt.Sprintf("%s/posts/%s", ms_posts_endpoint, id)
-	req, err := http.NewRequest(http.MethodDelete, url, nil)
+func (r *postRepository) DeletePost(ctx context.Context, id string) error {
+	req, err := r.client.NewRequest(ctx, http.MethodDelete, r.client.URL("/posts/%s", id), nil)
 	if err != nil {
 		return err
 	}
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := r.client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
-	return nil
+	return r.client.Check(resp)
 }
@@ -0,0 +1,55 @@
+package search
+
+import "sync"
+
+// Changes collects the kinds of documents written through the gateway, so
+// the index reloads them without waiting for its next refresh. A nil
+// *Changes ignores every call.
+type Changes struct {
+	wake chan struct{}
+
+	mu    sync.Mutex
+	kinds map[string]bool
+}
+
+func NewChanges() *Changes {
+	return &Changes{wake: make(chan struct{}, 1), kinds: make(map[string]bool)}
+}
+
+// Notify marks kind as changed.
+func (c *Changes) Notify(kind string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.kinds[kind] = true
+	c.mu.Unlock()
+	// Varias escrituras seguidas se juntan en una sola recarga.
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Wait receives after a Notify; it never does on a nil *Changes.
+func (c *Changes) Wait() <-chan struct{} {
+	if c == nil {
+		return nil
+	}
+	return c.wake
+}
+
+// Take returns the kinds changed since the last Take.
+func (c *Changes) Take() []string {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	kinds := make([]string, 0, len(c.kinds))
+	for kind := range c.kinds {
+		kinds = append(kinds, kind)
+	}
+	clear(c.kinds)
+	return kinds
+}
@@ -0,0 +1,40 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestChanges(t *testing.T) {
+	c := NewChanges()
+	c.Notify(KindPost)
+	c.Notify(KindRecipe)
+	c.Notify(KindPost)
+
+	select {
+	case <-c.Wait():
+	default:
+		t.Fatal("Wait() did not receive after Notify")
+	}
+	// Writes in a row wake the refresh once.
+	select {
+	case <-c.Wait():
+		t.Fatal("Wait() received twice for the same writes")
+	default:
+	}
+
+	kinds := c.Take()
+	slices.Sort(kinds)
+	if want := []string{KindPost, KindRecipe}; !slices.Equal(kinds, want) {
+		t.Fatalf("Take() = %v, want %v", kinds, want)
+	}
+	if kinds := c.Take(); len(kinds) != 0 {
+		t.Fatalf("second Take() = %v, want none", kinds)
+	}
+
+	var none *Changes
+	none.Notify(KindPost)
+	if none.Wait() != nil || none.Take() != nil {
+		t.Fatal("a nil *Changes must ignore every call")
+	}
+}
@@ -0,0 +1,183 @@
+package search
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Tipos de documento indexados.
+const (
+	KindLiquor = "liquor"
+	KindRecipe = "recipe"
+	KindPost   = "post"
+)
+
+// Puntaje de una palabra según cómo coincide con el término indexado.
+const (
+	exactScore  = 1.0
+	prefixScore = 0.7
+	fuzzyScore  = 0.5
+)
+
+type (
+	// Document is one searchable item. Fields maps the text of each field to
+	// its weight, so a match in a name ranks above one in a description.
+	Document struct {
+		Kind   string
+		ID     string
+		Title  string
+		Fields []Field
+		Item   interface{}
+	}
+
+	Field struct {
+		Text   string
+		Weight float64
+	}
+
+	Result struct {
+		Kind  string      `json:"type"`
+		ID    string      `json:"id"`
+		Title string      `json:"title"`
+		Score float64     `json:"score"`
+		Item  interface{} `json:"item"`
+	}
+
+	// Index is an in-memory inverted index. Replace swaps the whole content
+	// at once, so searches never see a half-built index.
+	Index struct {
+		mu       sync.RWMutex
+		docs     []Document
+		postings map[string][]posting
+		terms    []string
+	}
+
+	posting struct {
+		doc    int
+		weight float64
+	}
+)
+
+func NewIndex() *Index {
+	return &Index{postings: make(map[string][]posting)}
+}
+
+// Replace indexes docs, dropping everything indexed before.
+func (idx *Index) Replace(docs []Document) {
+	postings := make(map[string][]posting)
+	for i, doc := range docs {
+		// Cada término cuenta una vez por documento, con el peso del mejor campo.
+		best := make(map[string]float64)
+		for _, field := range doc.Fields {
+			for _, term := range Tokens(field.Text) {
+				best[term] = max(best[term], field.Weight)
+			}
+		}
+		for term, weight := range best {
+			postings[term] = append(postings[term], posting{doc: i, weight: weight})
+		}
+	}
+	terms := make([]string, 0, len(postings))
+	for term := range postings {
+		terms = append(terms, term)
+	}
+	slices.Sort(terms)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.docs = docs
+	idx.postings = postings
+	idx.terms = terms
+}
+
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// Search ranks the documents of the given kinds (all when empty) against
+// query. Every word may match exactly, as a prefix or with typos; documents
+// matching more of the words rank first.
+func (idx *Index) Search(query string, kinds []string, limit int) []Result {
+	words := Tokens(query)
+	if len(words) == 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	scores := make(map[int]float64)
+	matched := make(map[int]int)
+	for _, word := range words {
+		wordScores := make(map[int]float64)
+		for _, term := range idx.candidates(word) {
+			quality := matchQuality(word, term)
+			for _, p := range idx.postings[term] {
+				wordScores[p.doc] = max(wordScores[p.doc], quality*p.weight)
+			}
+		}
+		for doc, score := range wordScores {
+			scores[doc] += score
+			matched[doc]++
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for i, score := range scores {
+		doc := idx.docs[i]
+		if len(kinds) > 0 && !slices.Contains(kinds, doc.Kind) {
+			continue
+		}
+		coverage := float64(matched[i]) / float64(len(words))
+		results = append(results, Result{
+			Kind:  doc.Kind,
+			ID:    doc.ID,
+			Title: doc.Title,
+			Score: score / float64(len(words)) * coverage,
+			Item:  doc.Item,
+		})
+	}
+	slices.SortFunc(results, func(a, b Result) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return strings.Compare(Normalize(a.Title), Normalize(b.Title))
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// candidates returns the indexed terms that word may refer to.
+func (idx *Index) candidates(word string) []string {
+	var terms []string
+	// Los términos están ordenados: los que empiezan por word son contiguos.
+	start, _ := slices.BinarySearch(idx.terms, word)
+	for i := start; i < len(idx.terms) && strings.HasPrefix(idx.terms[i], word); i++ {
+		terms = append(terms, idx.terms[i])
+	}
+	if limit := maxEdits(word); limit > 0 {
+		for _, term := range idx.terms {
+			if !strings.HasPrefix(term, word) && distance(word, term, limit) <= limit {
+				terms = append(terms, term)
+			}
+		}
+	}
+	return terms
+}
+
+func matchQuality(word, term string) float64 {
+	switch {
+	case word == term:
+		return exactScore
+	case strings.HasPrefix(term, word):
+		return prefixScore
+	default:
+		return fuzzyScore / float64(distance(word, term, maxEdits(word)))
+	}
+}
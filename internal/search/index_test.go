@@ -0,0 +1,89 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func testIndex() *Index {
+	idx := NewIndex()
+	idx.Replace([]Document{
+		{Kind: KindLiquor, ID: "l1", Title: "Tequila Añejo", Fields: []Field{{Text: "Tequila Añejo", Weight: 3}, {Text: "Agave", Weight: 1}}},
+		{Kind: KindLiquor, ID: "l2", Title: "Mezcal", Fields: []Field{{Text: "Mezcal", Weight: 3}, {Text: "Agave ahumado", Weight: 1}}},
+		{Kind: KindLiquor, ID: "l3", Title: "Ron", Fields: []Field{{Text: "Ron", Weight: 3}, {Text: "Caña", Weight: 1}}},
+		{Kind: KindRecipe, ID: "r1", Title: "Margarita", Fields: []Field{{Text: "Margarita", Weight: 3}, {Text: "tequila, limón y triple sec", Weight: 1}}},
+		{Kind: KindRecipe, ID: "r2", Title: "Mojito", Fields: []Field{{Text: "Mojito", Weight: 3}, {Text: "ron, limón, menta", Weight: 1}}},
+		{Kind: KindPost, ID: "p1", Title: "Mi margarita favorita", Fields: []Field{{Text: "Mi margarita favorita", Weight: 2}}},
+	})
+	return idx
+}
+
+// expect runs query on idx and checks the ids returned, in order.
+func expect(t *testing.T, idx *Index, query string, kinds []string, limit int, want ...string) {
+	t.Helper()
+	ids := []string{}
+	for _, r := range idx.Search(query, kinds, limit) {
+		ids = append(ids, r.ID)
+	}
+	if want == nil {
+		want = []string{}
+	}
+	if !slices.Equal(ids, want) {
+		t.Errorf("Search(%q) = %v, want %v", query, ids, want)
+	}
+}
+
+func TestSearchIgnoresAccentsAndCase(t *testing.T) {
+	idx := testIndex()
+	expect(t, idx, "añejo", nil, 0, "l1")
+	expect(t, idx, "anejo", nil, 0, "l1")
+	expect(t, idx, "MOJITO", nil, 0, "r2")
+}
+
+func TestSearchToleratesTypos(t *testing.T) {
+	idx := testIndex()
+	expect(t, idx, "marg", nil, 0, "r1", "p1")
+	expect(t, idx, "mezcla", nil, 0, "l2")
+	expect(t, idx, "tequlia", nil, 0, "l1", "r1")
+	expect(t, idx, "margharitta", nil, 0, "r1", "p1")
+	// Short words must match exactly or as a prefix.
+	expect(t, idx, "rin", nil, 0)
+	expect(t, idx, "whisky", nil, 0)
+}
+
+func TestSearchRanking(t *testing.T) {
+	idx := testIndex()
+	// A match in the name outweighs one in the description.
+	expect(t, idx, "ron", nil, 0, "l3", "r2")
+	// Documents matching every word come first.
+	expect(t, idx, "tequila limon", nil, 0, "r1", "l1", "r2")
+	// Equal scores are ordered by title.
+	expect(t, idx, "agave", nil, 0, "l2", "l1")
+
+	score := func(query string) float64 {
+		results := idx.Search(query, []string{KindLiquor}, 1)
+		if len(results) == 0 {
+			t.Fatalf("Search(%q) found nothing", query)
+		}
+		return results[0].Score
+	}
+	if exact, prefix, fuzzy := score("mezcal"), score("mezc"), score("mescal"); !(exact > prefix && prefix > fuzzy) {
+		t.Errorf("scores exact %v, prefix %v, fuzzy %v are not in that order", exact, prefix, fuzzy)
+	}
+}
+
+func TestSearchKindsAndLimit(t *testing.T) {
+	idx := testIndex()
+	expect(t, idx, "margarita", []string{KindPost}, 0, "p1")
+	expect(t, idx, "agave", nil, 1, "l2")
+}
+
+func TestReplaceDropsOldDocuments(t *testing.T) {
+	idx := testIndex()
+	idx.Replace([]Document{{Kind: KindLiquor, ID: "l9", Title: "Ginebra", Fields: []Field{{Text: "Ginebra", Weight: 3}}}})
+	if idx.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1", idx.Len())
+	}
+	expect(t, idx, "mezcal", nil, 0)
+	expect(t, idx, "ginebra", nil, 0, "l9")
+}
@@ -0,0 +1,41 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokensStripAccentsAndPunctuation(t *testing.T) {
+	got := Tokens("Jugo de LIMÓN, piña-colada 30ml")
+	want := []string{"jugo", "de", "limon", "pina", "colada", "30ml"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Tokens() = %q, want %q", got, want)
+	}
+	if got := Tokens(" ¿? "); len(got) != 0 {
+		t.Fatalf("Tokens() = %q, want none", got)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	same := func(a, b string, limit, want int) {
+		t.Helper()
+		if got := distance(a, b, limit); got != want {
+			t.Errorf("distance(%q, %q, %d) = %d, want %d", a, b, limit, got, want)
+		}
+	}
+	same("mezcal", "mezcal", 2, 0)
+	same("mezcal", "mescal", 2, 1)
+	// Swapping two neighbours is a single edit.
+	same("mezcal", "mezcla", 2, 1)
+	same("tequila", "tekila", 2, 2)
+	// Past the limit the result is only limit+1.
+	same("ron", "ginebra", 2, 3)
+	same("aguardiente", "agua", 1, 2)
+}
+
+func TestMaxEditsGrowsWithTheWord(t *testing.T) {
+	if maxEdits("gin") != 0 || maxEdits("rons") != 1 || maxEdits("tequila") != 1 || maxEdits("aguardiente") != 2 {
+		t.Fatalf("maxEdits: gin %d, rons %d, tequila %d, aguardiente %d",
+			maxEdits("gin"), maxEdits("rons"), maxEdits("tequila"), maxEdits("aguardiente"))
+	}
+}
@@ -1,68 +1,165 @@
 package authservice
 
 import (
+	"context"
 	"errors"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/cache"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/dtos"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/repository/authrepository"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/tokens"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
 	"net/http"
+	"time"
 )
 
 type (
 	IAuth interface {
-		Verify(token string) utils.ApiError
-		Register(user dtos.Register) (*entities.User, utils.ApiError)
-		Login(credentails dtos.Login) (*entities.SuccessfulLogin, utils.ApiError)
-		GetUser(id string, token string) (*entities.User, utils.ApiError)
-		EditProfile(user dtos.User, token string) utils.ApiError
+		Verify(ctx context.Context, token string) utils.ApiError
+		Authenticate(ctx context.Context, token string) (*entities.Identity, utils.ApiError)
+		Register(ctx context.Context, user dtos.Register) (*entities.User, utils.ApiError)
+		Login(ctx context.Context, credentails dtos.Login) (*entities.SuccessfulLogin, utils.ApiError)
+		GetUser(ctx context.Context, id string, token string) (*entities.User, utils.ApiError)
+		EditProfile(ctx context.Context, user dtos.User, token string) utils.ApiError
 	}
 	authService struct {
-		authRepo authrepository.IAuth
+		authRepo       authrepository.IAuth
+		verifier       *tokens.Verifier
+		remoteFallback bool
+		cache          *tokens.Cache
+		sessions       *tokens.Sessions
+	}
+
+	Options struct {
+		// Verifier validates tokens locally; nil sends every token to the auth service.
+		Verifier       *tokens.Verifier
+		RemoteFallback bool
+		CacheTTL       time.Duration
+		// Sessions keeps the expiration returned by Login; a store shared
+		// between replicas shares it too.
+		Sessions cache.Store
+		// SessionMaxAge is how long a login is kept past its expiration.
+		SessionMaxAge time.Duration
 	}
 )
 
-func NewAuthService(repo authrepository.IAuth) IAuth {
-	return &authService{authRepo: repo}
+func NewAuthService(repo authrepository.IAuth, opts Options) IAuth {
+	return &authService{
+		authRepo:       repo,
+		verifier:       opts.Verifier,
+		remoteFallback: opts.RemoteFallback,
+		cache:          tokens.NewCache(opts.CacheTTL),
+		sessions:       tokens.NewSessions(opts.Sessions, opts.SessionMaxAge),
+	}
+}
+
+func (s *authService) Verify(ctx context.Context, token string) utils.ApiError {
+	_, apiErr := s.Authenticate(ctx, token)
+	return apiErr
 }
 
-func (s *authService) Verify(token string) utils.ApiError {
-	err := s.authRepo.Verify(token)
+func (s *authService) Authenticate(ctx context.Context, token string) (*entities.Identity, utils.ApiError) {
+	claims, err := s.authenticate(ctx, token)
 	if err != nil {
-		return utils.NewApiError(errors.New("unauthorized"), http.StatusUnauthorized)
+		if errors.Is(err, tokens.ErrExpired) {
+			return nil, utils.NewApiError(tokens.ErrExpired, http.StatusUnauthorized)
+		}
+		return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("unauthorized"), http.StatusUnauthorized))
 	}
-	return nil
+	return &entities.Identity{
+		UserID:      claims.Subject,
+		AccountType: claims.AccountType,
+		ExpiresAt:   claims.ExpiresAt,
+		Token:       token,
+	}, nil
+}
+
+// authenticate validates token locally when possible and falls back to the
+// auth service only when the verifier is absent or configured to allow it.
+func (s *authService) authenticate(ctx context.Context, token string) (*tokens.Claims, error) {
+	if token == "" {
+		return nil, errors.New("missing token")
+	}
+	if claims, ok := s.cache.Get(token); ok {
+		return claims, nil
+	}
+
+	session, hasSession := s.sessions.Lookup(ctx, token)
+	if hasSession && !session.ExpiresAt.IsZero() && time.Now().After(session.ExpiresAt) {
+		return nil, tokens.ErrExpired
+	}
+
+	if s.verifier != nil {
+		claims, err := s.verifier.Verify(ctx, token)
+		if err == nil {
+			if hasSession && !session.ExpiresAt.IsZero() && (claims.ExpiresAt.IsZero() || session.ExpiresAt.Before(claims.ExpiresAt)) {
+				claims.ExpiresAt = session.ExpiresAt
+			}
+			s.cache.Put(token, claims)
+			return claims, nil
+		}
+		if !s.remoteFallback || !canFallback(err) {
+			return nil, err
+		}
+	}
+
+	if err := s.authRepo.Verify(ctx, token); err != nil {
+		return nil, err
+	}
+	claims := &tokens.Claims{}
+	if hasSession {
+		claims = session
+	} else if parsed, err := tokens.ParseUnverified(token); err == nil {
+		claims = parsed
+	}
+	s.cache.Put(token, claims)
+	return claims, nil
 }
 
-func (s *authService) Register(user dtos.Register) (*entities.User, utils.ApiError) {
-	newUser, err := s.authRepo.Register(user)
+// Una firma inválida o un token vencido nunca se reintentan contra el servicio.
+func canFallback(err error) bool {
+	return errors.Is(err, tokens.ErrMalformed) ||
+		errors.Is(err, tokens.ErrUnsupportedAlg) ||
+		errors.Is(err, tokens.ErrUnknownKey) ||
+		errors.Is(err, tokens.ErrKeysUnavailable)
+}
+
+func (s *authService) Register(ctx context.Context, user dtos.Register) (*entities.User, utils.ApiError) {
+	newUser, err := s.authRepo.Register(ctx, user)
 	if err != nil {
-		return nil, utils.NewApiError(errors.New("register error"), http.StatusInternalServerError)
+		return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("register error"), http.StatusInternalServerError))
 	}
 	return newUser, nil
 }
 
-func (s *authService) Login(credentails dtos.Login) (*entities.SuccessfulLogin, utils.ApiError) {
-	loginResponse, err := s.authRepo.Login(credentails)
+func (s *authService) Login(ctx context.Context, credentails dtos.Login) (*entities.SuccessfulLogin, utils.ApiError) {
+	loginResponse, err := s.authRepo.Login(ctx, credentails)
 	if err != nil {
-		return nil, utils.NewApiError(errors.New("login error"), http.StatusInternalServerError)
+		return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("login error"), http.StatusInternalServerError))
+	}
+	if loginResponse.Token != "" {
+		session := &tokens.Claims{Subject: loginResponse.UserID, AccountType: loginResponse.AccountType}
+		if expiration, ok := tokens.ParseExpiration(loginResponse.Expiration); ok {
+			session.ExpiresAt = expiration
+		}
+		s.sessions.Remember(ctx, loginResponse.Token, session)
 	}
 	return loginResponse, nil
 }
 
-func (s *authService) GetUser(id string, token string) (*entities.User, utils.ApiError) {
-	user, err := s.authRepo.GetUser(id, token)
-	user.UserID = id
+func (s *authService) GetUser(ctx context.Context, id string, token string) (*entities.User, utils.ApiError) {
+	user, err := s.authRepo.GetUser(ctx, id, token)
 	if err != nil {
-		return nil, utils.NewApiError(errors.New("getting user error"), http.StatusInternalServerError)
+		return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("getting user error"), http.StatusInternalServerError))
 	}
+	user.UserID = id
 	return user, nil
 }
 
-func (s *authService) EditProfile(user dtos.User, token string) utils.ApiError {
-	err := s.authRepo.EditUser(user, token)
+func (s *authService) EditProfile(ctx context.Context, user dtos.User, token string) utils.ApiError {
+	err := s.authRepo.EditUser(ctx, user, token)
 	if err != nil {
-		return utils.NewApiError(errors.New("editing user error"), http.StatusInternalServerError)
+		return utils.FromUpstream(err, utils.NewApiError(errors.New("editing user error"), http.StatusInternalServerError))
 	}
 	return nil
 }
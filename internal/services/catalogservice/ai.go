@@ -1,6 +1,7 @@
 package catalogservice
 
 import (
+	"context"
 	"errors"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/repository/catalogrepository"
@@ -10,9 +11,9 @@ import (
 
 type (
 	IAI interface {
-		ProcessStrings(input []string) (string, utils.ApiError)
-		CreateRecipe(liquor string) (*entities.AIRecipe, utils.ApiError)
-		ExtractTextFromImage(imageBytes []byte) ([]string, utils.ApiError)
+		ProcessStrings(ctx context.Context, input []string) (string, utils.ApiError)
+		CreateRecipe(ctx context.Context, liquor string) (*entities.AIRecipe, utils.ApiError)
+		ExtractTextFromImage(ctx context.Context, imageBytes []byte) ([]string, utils.ApiError)
 	}
 	aiService struct {
 		aiRepository catalogrepository.IAI
@@ -23,26 +24,26 @@ func NewAIService(repo catalogrepository.IAI) IAI {
 	return &aiService{aiRepository: repo}
 }
 
-func (is *aiService) ProcessStrings(input []string) (string, utils.ApiError) {
-	result, err := is.aiRepository.ProcessStrings(input)
+func (is *aiService) ProcessStrings(ctx context.Context, input []string) (string, utils.ApiError) {
+	result, err := is.aiRepository.ProcessStrings(ctx, input)
 	if err != nil {
-		return "", utils.NewApiError(errors.New("error getting liquor"), http.StatusInternalServerError)
+		return "", utils.FromUpstream(err, utils.NewApiError(errors.New("error getting liquor"), http.StatusInternalServerError))
 	}
 	return result, nil
 }
 
-func (is *aiService) CreateRecipe(liquor string) (*entities.AIRecipe, utils.ApiError) {
-	recipe, err := is.aiRepository.CreateRecipe(liquor)
+func (is *aiService) CreateRecipe(ctx context.Context, liquor string) (*entities.AIRecipe, utils.ApiError) {
+	recipe, err := is.aiRepository.CreateRecipe(ctx, liquor)
 	if err != nil {
-		return nil, utils.NewApiError(errors.New("error generating recipe"), http.StatusInternalServerError)
+		return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("error generating recipe"), http.StatusInternalServerError))
 	}
 	return recipe, nil
 }
 
-func (is *aiService) ExtractTextFromImage(imageBytes []byte) ([]string, utils.ApiError) {
-	texts, err := is.aiRepository.ExtractTextFromImage(imageBytes)
+func (is *aiService) ExtractTextFromImage(ctx context.Context, imageBytes []byte) ([]string, utils.ApiError) {
+	texts, err := is.aiRepository.ExtractTextFromImage(ctx, imageBytes)
 	if err != nil {
-		return nil, utils.NewApiError(errors.New("error extracting text from image"), http.StatusInternalServerError)
+		return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("error extracting text from image"), http.StatusInternalServerError))
 	}
 	return texts, nil
 }
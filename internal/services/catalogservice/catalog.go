@@ -1,65 +1,103 @@
 package catalogservice
 
 import (
+	"context"
 	"errors"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/dtos"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/identity"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/listing"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/policy"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/repository/catalogrepository"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/search"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
 	"net/http"
 )
 
 type (
 	ICatalog interface {
-		GetLiquors() ([]entities.Liquor, utils.ApiError)
-		GetLiquorByID(id string) (*entities.Liquor, utils.ApiError)
-		CreateLiquor(liquor dtos.Liquor) (*entities.Liquor, utils.ApiError)
-		UpdateLiquor(id string, updates map[string]interface{}) (*entities.Liquor, utils.ApiError)
-		DeleteLiquor(id string) utils.ApiError
-		GetRecipes() ([]entities.Recipe, utils.ApiError)
-		GetRecipeByID(id string) (*entities.Recipe, utils.ApiError)
-		CreateRecipe(recipe dtos.Recipe) (*entities.Recipe, utils.ApiError)
-		UpdateRecipe(id string, updates map[string]interface{}) (*entities.Recipe, utils.ApiError)
-		DeleteRecipe(id string) utils.ApiError
+		GetLiquors(ctx context.Context) ([]entities.Liquor, utils.ApiError)
+		ListLiquors(ctx context.Context, q listing.Query) ([]entities.Liquor, *listing.Page, utils.ApiError)
+		GetLiquorByID(ctx context.Context, id string) (*entities.Liquor, utils.ApiError)
+		CreateLiquor(ctx context.Context, liquor dtos.Liquor) (*entities.Liquor, utils.ApiError)
+		UpdateLiquor(ctx context.Context, id string, updates map[string]interface{}) (*entities.Liquor, utils.ApiError)
+		DeleteLiquor(ctx context.Context, id string) utils.ApiError
+		GetRecipes(ctx context.Context) ([]entities.Recipe, utils.ApiError)
+		ListRecipes(ctx context.Context, q listing.Query) ([]entities.Recipe, *listing.Page, utils.ApiError)
+		GetRecipeByID(ctx context.Context, id string) (*entities.Recipe, utils.ApiError)
+		CreateRecipe(ctx context.Context, recipe dtos.Recipe) (*entities.Recipe, utils.ApiError)
+		UpdateRecipe(ctx context.Context, id string, updates map[string]interface{}) (*entities.Recipe, utils.ApiError)
+		DeleteRecipe(ctx context.Context, id string) utils.ApiError
+	}
+
+	Options struct {
+		// ListPassthrough forwards sorting, filters and pagination to the
+		// catalog service, which must implement them; the gateway applies
+		// sorting and filters again anyway.
+		ListPassthrough bool
+		// Changes hears about every liquor and recipe write, for the search
+		// index.
+		Changes *search.Changes
 	}
 
 	catalogService struct {
 		catalogRepository catalogrepository.ICatalog
+		listPassthrough   bool
+		changes           *search.Changes
 	}
 )
 
-func NewCatalogService(repo catalogrepository.ICatalog) ICatalog {
-	return &catalogService{catalogRepository: repo}
+func NewCatalogService(repo catalogrepository.ICatalog, opts Options) ICatalog {
+	return &catalogService{catalogRepository: repo, listPassthrough: opts.ListPassthrough, changes: opts.Changes}
 }
 
-func (cs *catalogService) GetLiquors() ([]entities.Liquor, utils.ApiError) {
-	liquors, err := cs.catalogRepository.FetchLiquors()
+func (cs *catalogService) GetLiquors(ctx context.Context) ([]entities.Liquor, utils.ApiError) {
+	liquors, err := cs.catalogRepository.FetchLiquors(ctx)
 	if err != nil {
-		return nil, utils.NewApiError(errors.New("error getting liquors"), http.StatusInternalServerError)
+		return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("error getting liquors"), http.StatusInternalServerError))
 	}
 	return liquors, nil
 }
 
-func (cs *catalogService) GetLiquorByID(id string) (*entities.Liquor, utils.ApiError) {
-	liquor, err := cs.catalogRepository.FetchLiquorByID(id)
+func (cs *catalogService) ListLiquors(ctx context.Context, q listing.Query) ([]entities.Liquor, *listing.Page, utils.ApiError) {
+	q, err := q.Resolve()
 	if err != nil {
-		return nil, utils.NewApiError(errors.New("liquor not found"), http.StatusNotFound)
+		return nil, nil, utils.NewApiError(err, http.StatusBadRequest)
+	}
+	var liquors []entities.Liquor
+	total := -1
+	if querier, ok := cs.catalogRepository.(catalogrepository.ListQuerier); ok && cs.listPassthrough {
+		liquors, total, err = querier.QueryLiquors(ctx, q.PageValues())
+	} else {
+		liquors, err = cs.catalogRepository.FetchLiquors(ctx)
+	}
+	if err != nil {
+		return nil, nil, utils.FromUpstream(err, utils.NewApiError(errors.New("error getting liquors"), http.StatusInternalServerError))
+	}
+	return paginate(liquors, total, q, listing.Liquors)
+}
+
+func (cs *catalogService) GetLiquorByID(ctx context.Context, id string) (*entities.Liquor, utils.ApiError) {
+	liquor, err := cs.catalogRepository.FetchLiquorByID(ctx, id)
+	if err != nil {
+		return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("liquor not found"), http.StatusNotFound))
 	}
 	return liquor, nil
 }
 
-func (cs *catalogService) CreateLiquor(liquor dtos.Liquor) (*entities.Liquor, utils.ApiError) {
-	newLiquor, err := cs.catalogRepository.CreateLiquor(liquor)
+func (cs *catalogService) CreateLiquor(ctx context.Context, liquor dtos.Liquor) (*entities.Liquor, utils.ApiError) {
+	newLiquor, err := cs.catalogRepository.CreateLiquor(ctx, liquor)
 	if err != nil {
-		return nil, utils.NewApiError(errors.New("error saving liquor"), http.StatusInternalServerError)
+		return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("error saving liquor"), http.StatusInternalServerError))
 	}
+	cs.changes.Notify(search.KindLiquor)
 	return newLiquor, nil
 }
 
-func (cs *catalogService) UpdateLiquor(id string, updates map[string]interface{}) (*entities.Liquor, utils.ApiError) {
-	currentLiquor, apiErr := cs.catalogRepository.FetchLiquorByID(id)
+func (cs *catalogService) UpdateLiquor(ctx context.Context, id string, updates map[string]interface{}) (*entities.Liquor, utils.ApiError) {
+	currentLiquor, apiErr := cs.catalogRepository.FetchLiquorByID(ctx, id)
 	if apiErr != nil {
-		return nil, utils.NewApiError(apiErr, http.StatusNotFound)
+		return nil, utils.FromUpstream(apiErr, utils.NewApiError(errors.New("liquor not found"), http.StatusNotFound))
 	}
 	updatedFields := make(map[string]interface{})
 
@@ -83,58 +121,131 @@ func (cs *catalogService) UpdateLiquor(id string, updates map[string]interface{}
 		return currentLiquor, nil
 	}
 
-	updatedLiquor, err := cs.catalogRepository.UpdateLiquor(id, updatedFields)
+	updatedLiquor, err := cs.catalogRepository.UpdateLiquor(ctx, id, updatedFields)
 	if err != nil {
-		return nil, utils.NewApiError(err, http.StatusInternalServerError)
+		return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("error updating liquor"), http.StatusInternalServerError))
 	}
 
+	cs.changes.Notify(search.KindLiquor)
 	return updatedLiquor, nil
 }
 
-func (cs *catalogService) DeleteLiquor(id string) utils.ApiError {
-	err := cs.catalogRepository.DeleteLiquor(id)
+func (cs *catalogService) DeleteLiquor(ctx context.Context, id string) utils.ApiError {
+	err := cs.catalogRepository.DeleteLiquor(ctx, id)
 	if err != nil {
-		return utils.NewApiError(errors.New("error deleting liquor"), http.StatusInternalServerError)
+		return utils.FromUpstream(err, utils.NewApiError(errors.New("error deleting liquor"), http.StatusInternalServerError))
 	}
+	cs.changes.Notify(search.KindLiquor)
 	return nil
 }
 
-func (cs *catalogService) GetRecipes() ([]entities.Recipe, utils.ApiError) {
-	recipes, err := cs.catalogRepository.FetchRecipes()
+func (cs *catalogService) GetRecipes(ctx context.Context) ([]entities.Recipe, utils.ApiError) {
+	recipes, err := cs.catalogRepository.FetchRecipes(ctx)
 	if err != nil {
-		return nil, utils.NewApiError(errors.New("error getting recipes"), http.StatusInternalServerError)
+		return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("error getting recipes"), http.StatusInternalServerError))
 	}
 	return recipes, nil
 }
 
-func (cs *catalogService) GetRecipeByID(id string) (*entities.Recipe, utils.ApiError) {
-	recipe, err := cs.catalogRepository.FetchRecipeByID(id)
+func (cs *catalogService) ListRecipes(ctx context.Context, q listing.Query) ([]entities.Recipe, *listing.Page, utils.ApiError) {
+	q, err := q.Resolve()
+	if err != nil {
+		return nil, nil, utils.NewApiError(err, http.StatusBadRequest)
+	}
+	var recipes []entities.Recipe
+	total := -1
+	if querier, ok := cs.catalogRepository.(catalogrepository.ListQuerier); ok && cs.listPassthrough {
+		recipes, total, err = querier.QueryRecipes(ctx, q.PageValues())
+	} else {
+		recipes, err = cs.catalogRepository.FetchRecipes(ctx)
+	}
+	if err != nil {
+		return nil, nil, utils.FromUpstream(err, utils.NewApiError(errors.New("error getting recipes"), http.StatusInternalServerError))
+	}
+	return paginate(recipes, total, q, listing.Recipes)
+}
+
+func (cs *catalogService) GetRecipeByID(ctx context.Context, id string) (*entities.Recipe, utils.ApiError) {
+	recipe, err := cs.catalogRepository.FetchRecipeByID(ctx, id)
 	if err != nil {
-		return nil, utils.NewApiError(errors.New("recipe not found"), http.StatusNotFound)
+		return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("recipe not found"), http.StatusNotFound))
 	}
 	return recipe, nil
 }
 
-func (cs *catalogService) CreateRecipe(recipe dtos.Recipe) (*entities.Recipe, utils.ApiError) {
-	newRecipe, err := cs.catalogRepository.CreateRecipe(recipe)
+func (cs *catalogService) CreateRecipe(ctx context.Context, recipe dtos.Recipe) (*entities.Recipe, utils.ApiError) {
+	caller, _ := identity.FromContext(ctx)
+	if apiErr := policy.Authorize(caller, policy.RecipeCreate, ""); apiErr != nil {
+		return nil, apiErr
+	}
+	// El creador sale del token, nunca del body.
+	recipe.CreatorId = caller.UserID
+
+	newRecipe, err := cs.catalogRepository.CreateRecipe(ctx, recipe)
 	if err != nil {
-		return nil, utils.NewApiError(errors.New("error saving recipe"), http.StatusInternalServerError)
+		return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("error saving recipe"), http.StatusInternalServerError))
 	}
+	cs.changes.Notify(search.KindRecipe)
 	return newRecipe, nil
 }
 
-func (cs *catalogService) UpdateRecipe(id string, updates map[string]interface{}) (*entities.Recipe, utils.ApiError) {
-	updatedRecipe, err := cs.catalogRepository.UpdateRecipe(id, updates)
+func (cs *catalogService) UpdateRecipe(ctx context.Context, id string, updates map[string]interface{}) (*entities.Recipe, utils.ApiError) {
+	if apiErr := cs.authorizeRecipeEdit(ctx, id); apiErr != nil {
+		return nil, apiErr
+	}
+	delete(updates, "creatorId")
+
+	updatedRecipe, err := cs.catalogRepository.UpdateRecipe(ctx, id, updates)
 	if err != nil {
-		return nil, utils.NewApiError(err, http.StatusInternalServerError)
+		return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("error updating recipe"), http.StatusInternalServerError))
 	}
+	cs.changes.Notify(search.KindRecipe)
 	return updatedRecipe, nil
 }
 
-func (cs *catalogService) DeleteRecipe(id string) utils.ApiError {
-	err := cs.catalogRepository.DeleteRecipe(id)
+func (cs *catalogService) DeleteRecipe(ctx context.Context, id string) utils.ApiError {
+	if apiErr := cs.authorizeRecipeEdit(ctx, id); apiErr != nil {
+		return apiErr
+	}
+
+	err := cs.catalogRepository.DeleteRecipe(ctx, id)
 	if err != nil {
-		return utils.NewApiError(errors.New("error deleting recipe"), http.StatusInternalServerError)
+		return utils.FromUpstream(err, utils.NewApiError(errors.New("error deleting recipe"), http.StatusInternalServerError))
 	}
+	cs.changes.Notify(search.KindRecipe)
 	return nil
 }
+
+// authorizeRecipeEdit lets admins through and otherwise loads the recipe to
+// check that the caller created it.
+func (cs *catalogService) authorizeRecipeEdit(ctx context.Context, id string) utils.ApiError {
+	caller, _ := identity.FromContext(ctx)
+	if caller == nil || policy.HasRole(caller, policy.RecipeEdit.Roles...) {
+		return policy.Authorize(caller, policy.RecipeEdit, "")
+	}
+
+	recipe, err := cs.catalogRepository.FetchRecipeByID(ctx, id)
+	if err != nil {
+		return utils.FromUpstream(err, utils.NewApiError(errors.New("recipe not found"), http.StatusNotFound))
+	}
+	return policy.Authorize(caller, policy.RecipeEdit, recipe.CreatorId)
+}
+
+// paginate applies q to items with list. When the catalog service already
+// cut the page (it sent a total and honoured the limit) only the sorting
+// and filters are applied again, and the page comes from its total.
+func paginate[T any](items []T, total int, q listing.Query, list func([]T, listing.Query) ([]T, listing.Page, error)) ([]T, *listing.Page, utils.ApiError) {
+	paged := total >= 0 && (q.Limit == 0 || len(items) <= q.Limit)
+	unpaged := q
+	if paged {
+		unpaged.Limit, unpaged.Offset = 0, 0
+	}
+	items, page, err := list(items, unpaged)
+	if err != nil {
+		return nil, nil, utils.NewApiError(err, http.StatusBadRequest)
+	}
+	if paged {
+		page = listing.PageOf(q, len(items), total)
+	}
+	return items, &page, nil
+}
@@ -0,0 +1,104 @@
+package catalogservice
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/dtos"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/identity"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/policy"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/repository/catalogrepository"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/upstream"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+)
+
+// fakeRecipes knows recipe r1, created by u1.
+type fakeRecipes struct {
+	catalogrepository.ICatalog
+	created dtos.Recipe
+	updates map[string]interface{}
+	written bool
+}
+
+func (r *fakeRecipes) FetchRecipeByID(_ context.Context, id string) (*entities.Recipe, error) {
+	if id != "r1" {
+		return nil, &upstream.Error{Upstream: "catalog", Kind: upstream.KindNotFound, Status: http.StatusNotFound}
+	}
+	return &entities.Recipe{ID: id, CreatorId: "u1"}, nil
+}
+
+func (r *fakeRecipes) CreateRecipe(_ context.Context, recipe dtos.Recipe) (*entities.Recipe, error) {
+	r.created, r.written = recipe, true
+	return &entities.Recipe{ID: "r2", CreatorId: recipe.CreatorId}, nil
+}
+
+func (r *fakeRecipes) UpdateRecipe(_ context.Context, id string, updates map[string]interface{}) (*entities.Recipe, error) {
+	r.updates, r.written = updates, true
+	return &entities.Recipe{ID: id, CreatorId: "u1"}, nil
+}
+
+func (r *fakeRecipes) DeleteRecipe(context.Context, string) error {
+	r.written = true
+	return nil
+}
+
+func TestRecipeOwnership(t *testing.T) {
+	as := func(userID, accountType string) context.Context {
+		return identity.NewContext(context.Background(), &entities.Identity{UserID: userID, AccountType: accountType})
+	}
+	edits := map[string]func(ICatalog, context.Context, string) utils.ApiError{
+		"update": func(s ICatalog, ctx context.Context, id string) utils.ApiError {
+			_, apiErr := s.UpdateRecipe(ctx, id, map[string]interface{}{"name": "n", "creatorId": "u2"})
+			return apiErr
+		},
+		"delete": func(s ICatalog, ctx context.Context, id string) utils.ApiError {
+			return s.DeleteRecipe(ctx, id)
+		},
+	}
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		id     string
+		status int
+	}{
+		{"creator", as("u1", policy.RoleUser), "r1", 0},
+		{"another user", as("u2", policy.RoleUser), "r1", http.StatusForbidden},
+		{"admin", as("a1", policy.RoleAdmin), "r1", 0},
+		{"no identity", context.Background(), "r1", http.StatusUnauthorized},
+		{"unknown recipe", as("u2", policy.RoleUser), "r9", http.StatusNotFound},
+	}
+	for op, edit := range edits {
+		for _, tt := range tests {
+			t.Run(op+"/"+tt.name, func(t *testing.T) {
+				repo := &fakeRecipes{}
+				apiErr := edit(NewCatalogService(repo, Options{}), tt.ctx, tt.id)
+				status := 0
+				if apiErr != nil {
+					status = apiErr.Status()
+				}
+				if status != tt.status {
+					t.Fatalf("status = %d, want %d", status, tt.status)
+				}
+				if repo.written != (tt.status == 0) {
+					t.Fatalf("recipe written = %v, want %v", repo.written, tt.status == 0)
+				}
+				if _, ok := repo.updates["creatorId"]; ok {
+					t.Fatal("the update changed the creator")
+				}
+			})
+		}
+	}
+}
+
+func TestCreateRecipeIgnoresTheBodyCreator(t *testing.T) {
+	repo := &fakeRecipes{}
+	ctx := identity.NewContext(context.Background(), &entities.Identity{UserID: "u1", AccountType: policy.RoleUser})
+	if _, apiErr := NewCatalogService(repo, Options{}).CreateRecipe(ctx, dtos.Recipe{Name: "n", CreatorId: "u2"}); apiErr != nil {
+		t.Fatalf("CreateRecipe() error = %v", apiErr)
+	}
+	if repo.created.CreatorId != "u1" {
+		t.Fatalf("created recipe creator = %q, want u1", repo.created.CreatorId)
+	}
+}
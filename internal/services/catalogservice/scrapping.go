@@ -1,6 +1,7 @@
 package catalogservice
 
 import (
+	"context"
 	"errors"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/repository/catalogrepository"
@@ -10,7 +11,7 @@ import (
 
 type (
 	IScrapping interface {
-		GetProductByCode(code string) (*entities.Product, utils.ApiError)
+		GetProductByCode(ctx context.Context, code string) (*entities.Product, utils.ApiError)
 	}
 	scrappingService struct {
 		scrappingRepository catalogrepository.IScrapping
@@ -21,10 +22,10 @@ func NewScrappingService(repo catalogrepository.IScrapping) IScrapping {
 	return &scrappingService{scrappingRepository: repo}
 }
 
-func (ss *scrappingService) GetProductByCode(code string) (*entities.Product, utils.ApiError) {
-	product, err := ss.scrappingRepository.GetProductByCode(code)
+func (ss *scrappingService) GetProductByCode(ctx context.Context, code string) (*entities.Product, utils.ApiError) {
+	product, err := ss.scrappingRepository.GetProductByCode(ctx, code)
 	if err != nil {
-		return nil, utils.NewApiError(errors.New("product not found"), http.StatusNotFound)
+		return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("product not found"), http.StatusNotFound))
 	}
 	return product, nil
 }
@@ -0,0 +1,188 @@
+package postservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/identity"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/policy"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/upstream"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+)
+
+const (
+	MaxCommentLength  = 1000
+	MaxReactionLength = 32
+
+	// Un cambio que choca con otra escritura se vuelve a aplicar sobre el
+	// post recién leído, hasta esta cantidad de intentos.
+	maxInteractAttempts = 3
+)
+
+var errCommentNotFound = errors.New("comment not found")
+
+// change edits the interactions of a post for caller and reports whether
+// anything changed, so repeated likes or reactions do not reach the upstream.
+type change func(caller *entities.Identity, interactions []entities.Interaction) ([]entities.Interaction, bool, utils.ApiError)
+
+func (s *postsService) Like(ctx context.Context, id string) (*entities.Post, utils.ApiError) {
+	return s.interact(ctx, id, add(entities.InteractionLike, ""))
+}
+
+func (s *postsService) Unlike(ctx context.Context, id string) (*entities.Post, utils.ApiError) {
+	return s.interact(ctx, id, remove(entities.InteractionLike, ""))
+}
+
+func (s *postsService) Comment(ctx context.Context, id string, text string) (*entities.Post, utils.ApiError) {
+	text = strings.TrimSpace(text)
+	if apiErr := validateValue("comment", text, MaxCommentLength); apiErr != nil {
+		return nil, apiErr
+	}
+	return s.interact(ctx, id, func(caller *entities.Identity, interactions []entities.Interaction) ([]entities.Interaction, bool, utils.ApiError) {
+		return append(interactions, newInteraction(caller, entities.InteractionComment, text)), true, nil
+	})
+}
+
+func (s *postsService) DeleteComment(ctx context.Context, id string, createdAt string) (*entities.Post, utils.ApiError) {
+	return s.interact(ctx, id, func(caller *entities.Identity, interactions []entities.Interaction) ([]entities.Interaction, bool, utils.ApiError) {
+		// Si dos comentarios tienen el mismo createdAt se prefiere el del usuario.
+		match := -1
+		for i, interaction := range interactions {
+			if interaction.Type != entities.InteractionComment || interaction.CreatedAt != createdAt {
+				continue
+			}
+			if interaction.UserId == caller.UserID {
+				match = i
+				break
+			}
+			if match < 0 {
+				match = i
+			}
+		}
+		if match < 0 {
+			return nil, false, utils.NewApiError(errCommentNotFound, http.StatusNotFound)
+		}
+		if apiErr := policy.Authorize(caller, policy.CommentDelete, interactions[match].UserId); apiErr != nil {
+			return nil, false, apiErr
+		}
+		return append(interactions[:match:match], interactions[match+1:]...), true, nil
+	})
+}
+
+func (s *postsService) React(ctx context.Context, id string, reaction string) (*entities.Post, utils.ApiError) {
+	reaction = strings.TrimSpace(reaction)
+	if apiErr := validateValue("reaction", reaction, MaxReactionLength); apiErr != nil {
+		return nil, apiErr
+	}
+	return s.interact(ctx, id, add(entities.InteractionReaction, reaction))
+}
+
+func (s *postsService) Unreact(ctx context.Context, id string, reaction string) (*entities.Post, utils.ApiError) {
+	return s.interact(ctx, id, remove(entities.InteractionReaction, strings.TrimSpace(reaction)))
+}
+
+// interact loads the post, applies change for the caller from the token and
+// saves the new interactions through the posts service. The save carries the
+// ETag of the post that was read as If-Match, so a write from another replica
+// in between makes it start over instead of being lost. A posts service that
+// sends no ETag only gets the per-process lock.
+func (s *postsService) interact(ctx context.Context, id string, change change) (*entities.Post, utils.ApiError) {
+	caller, _ := identity.FromContext(ctx)
+	if apiErr := policy.Authorize(caller, policy.PostInteract, ""); apiErr != nil {
+		return nil, apiErr
+	}
+
+	lock := s.lock(id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		post, etag, err := s.repo.FetchPostVersion(ctx, id)
+		if err != nil {
+			return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("post not found"), http.StatusNotFound))
+		}
+		interactions, changed, apiErr := change(caller, post.Interactions)
+		if apiErr != nil {
+			return nil, apiErr
+		}
+		if !changed {
+			post.Count()
+			return post, nil
+		}
+		if interactions == nil {
+			interactions = []entities.Interaction{}
+		}
+
+		updatedPost, err := s.repo.UpdatePostIfMatch(ctx, id, etag, map[string]interface{}{"interactions": interactions})
+		if etag != "" && attempt < maxInteractAttempts && upstream.IsKind(err, upstream.KindConflict) {
+			continue
+		}
+		if err != nil {
+			return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("error updating post interactions"), http.StatusInternalServerError))
+		}
+		updatedPost.Count()
+		return updatedPost, nil
+	}
+}
+
+func (s *postsService) lock(id string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return &s.locks[h.Sum32()%uint32(len(s.locks))]
+}
+
+// add records a like, or a reaction with value, once per user.
+func add(t entities.InteractionType, value string) change {
+	return func(caller *entities.Identity, interactions []entities.Interaction) ([]entities.Interaction, bool, utils.ApiError) {
+		if indexOf(interactions, t, caller.UserID, value) >= 0 {
+			return interactions, false, nil
+		}
+		return append(interactions, newInteraction(caller, t, value)), true, nil
+	}
+}
+
+func remove(t entities.InteractionType, value string) change {
+	return func(caller *entities.Identity, interactions []entities.Interaction) ([]entities.Interaction, bool, utils.ApiError) {
+		i := indexOf(interactions, t, caller.UserID, value)
+		if i < 0 {
+			return interactions, false, nil
+		}
+		return append(interactions[:i:i], interactions[i+1:]...), true, nil
+	}
+}
+
+func indexOf(interactions []entities.Interaction, t entities.InteractionType, userID, value string) int {
+	for i, interaction := range interactions {
+		if interaction.Type == t && interaction.UserId == userID && interaction.Value == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func newInteraction(caller *entities.Identity, t entities.InteractionType, value string) entities.Interaction {
+	return entities.Interaction{
+		Type:      t,
+		Value:     value,
+		UserId:    caller.UserID,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339Nano),
+	}
+}
+
+func validateValue(name, value string, maxLength int) utils.ApiError {
+	if value == "" {
+		return utils.NewApiError(fmt.Errorf("%s must not be empty", name), http.StatusBadRequest)
+	}
+	if utf8.RuneCountInString(value) > maxLength {
+		return utils.NewApiError(fmt.Errorf("%s must not exceed %d characters", name, maxLength), http.StatusBadRequest)
+	}
+	return nil
+}
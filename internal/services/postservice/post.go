@@ -1,67 +1,133 @@
 package postservice
 
 import (
+	"context"
 	"errors"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/dtos"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/identity"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/policy"
 	"github.com/Cococtel/Cococtel_Gagateway/internal/repository/postrepository"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/search"
 	"net/http"
+	"sync"
 
 	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
 )
 
 type PostsService interface {
-	GetPosts() ([]entities.Post, utils.ApiError)
-	GetPostByID(id string) (*entities.Post, utils.ApiError)
-	CreatePost(post dtos.Post) (*entities.Post, utils.ApiError)
-	UpdatePost(id string, updates map[string]interface{}) (*entities.Post, utils.ApiError)
-	DeletePost(id string) utils.ApiError
+	GetPosts(ctx context.Context) ([]entities.Post, utils.ApiError)
+	GetPostByID(ctx context.Context, id string) (*entities.Post, utils.ApiError)
+	CreatePost(ctx context.Context, post dtos.Post) (*entities.Post, utils.ApiError)
+	UpdatePost(ctx context.Context, id string, updates map[string]interface{}) (*entities.Post, utils.ApiError)
+	DeletePost(ctx context.Context, id string) utils.ApiError
+	Like(ctx context.Context, id string) (*entities.Post, utils.ApiError)
+	Unlike(ctx context.Context, id string) (*entities.Post, utils.ApiError)
+	Comment(ctx context.Context, id string, text string) (*entities.Post, utils.ApiError)
+	// DeleteComment removes the comment left at createdAt by the caller, or
+	// by anyone when the caller is an admin.
+	DeleteComment(ctx context.Context, id string, createdAt string) (*entities.Post, utils.ApiError)
+	React(ctx context.Context, id string, reaction string) (*entities.Post, utils.ApiError)
+	Unreact(ctx context.Context, id string, reaction string) (*entities.Post, utils.ApiError)
+}
+
+type Options struct {
+	// Changes is told when a post is created, edited or deleted, so /search
+	// reloads posts.
+	Changes *search.Changes
 }
 
 type postsService struct {
-	repo postrepository.IPost
+	repo    postrepository.IPost
+	changes *search.Changes
+	// locks serializes the interaction changes of each post in this process,
+	// which saves conflicting writes; across replicas only If-Match does.
+	locks [64]sync.Mutex
 }
 
-func NewPostsService(repo postrepository.IPost) PostsService {
-	return &postsService{repo: repo}
+func NewPostsService(repo postrepository.IPost, opts Options) PostsService {
+	return &postsService{repo: repo, changes: opts.Changes}
 }
 
-func (s *postsService) GetPosts() ([]entities.Post, utils.ApiError) {
-	posts, err := s.repo.FetchPosts()
+func (s *postsService) GetPosts(ctx context.Context) ([]entities.Post, utils.ApiError) {
+	posts, err := s.repo.FetchPosts(ctx)
 	if err != nil {
-		return nil, utils.NewApiError(errors.New("error fetching posts"), http.StatusInternalServerError)
+		return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("error fetching posts"), http.StatusInternalServerError))
+	}
+	for i := range posts {
+		posts[i].Count()
 	}
 	return posts, nil
 }
 
-func (s *postsService) GetPostByID(id string) (*entities.Post, utils.ApiError) {
-	post, err := s.repo.FetchPostByID(id)
+func (s *postsService) GetPostByID(ctx context.Context, id string) (*entities.Post, utils.ApiError) {
+	post, err := s.repo.FetchPostByID(ctx, id)
 	if err != nil {
-		return nil, utils.NewApiError(errors.New("post not found"), http.StatusNotFound)
+		return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("post not found"), http.StatusNotFound))
 	}
+	post.Count()
 	return post, nil
 }
 
-func (s *postsService) CreatePost(post dtos.Post) (*entities.Post, utils.ApiError) {
-	newPost, err := s.repo.CreatePost(post)
+func (s *postsService) CreatePost(ctx context.Context, post dtos.Post) (*entities.Post, utils.ApiError) {
+	caller, _ := identity.FromContext(ctx)
+	if apiErr := policy.Authorize(caller, policy.PostCreate, ""); apiErr != nil {
+		return nil, apiErr
+	}
+	// El autor sale del token, nunca del body.
+	post.Author = caller.UserID
+
+	newPost, err := s.repo.CreatePost(ctx, post)
 	if err != nil {
-		return nil, utils.NewApiError(errors.New("error creating post"), http.StatusInternalServerError)
+		return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("error creating post"), http.StatusInternalServerError))
 	}
+	s.changes.Notify(search.KindPost)
+	newPost.Count()
 	return newPost, nil
 }
 
-func (s *postsService) UpdatePost(id string, updates map[string]interface{}) (*entities.Post, utils.ApiError) {
-	updatedPost, err := s.repo.UpdatePost(id, updates)
+func (s *postsService) UpdatePost(ctx context.Context, id string, updates map[string]interface{}) (*entities.Post, utils.ApiError) {
+	if apiErr := s.authorizePostEdit(ctx, id); apiErr != nil {
+		return nil, apiErr
+	}
+	// Las interacciones solo cambian con Like, Comment y React.
+	for _, field := range []string{"author", "interactions", "likeCount", "commentCount"} {
+		delete(updates, field)
+	}
+
+	updatedPost, err := s.repo.UpdatePost(ctx, id, updates)
 	if err != nil {
-		return nil, utils.NewApiError(errors.New("error updating post"), http.StatusInternalServerError)
+		return nil, utils.FromUpstream(err, utils.NewApiError(errors.New("error updating post"), http.StatusInternalServerError))
 	}
+	s.changes.Notify(search.KindPost)
+	updatedPost.Count()
 	return updatedPost, nil
 }
 
-func (s *postsService) DeletePost(id string) utils.ApiError {
-	err := s.repo.DeletePost(id)
+func (s *postsService) DeletePost(ctx context.Context, id string) utils.ApiError {
+	if apiErr := s.authorizePostEdit(ctx, id); apiErr != nil {
+		return apiErr
+	}
+
+	err := s.repo.DeletePost(ctx, id)
 	if err != nil {
-		return utils.NewApiError(errors.New("error deleting post"), http.StatusInternalServerError)
+		return utils.FromUpstream(err, utils.NewApiError(errors.New("error deleting post"), http.StatusInternalServerError))
 	}
+	s.changes.Notify(search.KindPost)
 	return nil
 }
+
+// authorizePostEdit lets admins through and otherwise loads the post to
+// check that the caller wrote it.
+func (s *postsService) authorizePostEdit(ctx context.Context, id string) utils.ApiError {
+	caller, _ := identity.FromContext(ctx)
+	if caller == nil || policy.HasRole(caller, policy.PostEdit.Roles...) {
+		return policy.Authorize(caller, policy.PostEdit, "")
+	}
+
+	post, err := s.repo.FetchPostByID(ctx, id)
+	if err != nil {
+		return utils.FromUpstream(err, utils.NewApiError(errors.New("post not found"), http.StatusNotFound))
+	}
+	return policy.Authorize(caller, policy.PostEdit, post.Author)
+}
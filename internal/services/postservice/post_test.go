@@ -0,0 +1,121 @@
+package postservice
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/dtos"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/identity"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/policy"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/repository/postrepository"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/upstream"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+)
+
+// fakePosts holds one post written by u1 and records the writes that reach it.
+type fakePosts struct {
+	postrepository.IPost
+	created dtos.Post
+	updates map[string]interface{}
+	writes  int
+}
+
+func (r *fakePosts) FetchPostByID(_ context.Context, id string) (*entities.Post, error) {
+	if id != "p1" {
+		return nil, &upstream.Error{Upstream: "posts", Kind: upstream.KindNotFound, Status: http.StatusNotFound}
+	}
+	return &entities.Post{ID: "p1", Author: "u1"}, nil
+}
+
+func (r *fakePosts) CreatePost(_ context.Context, post dtos.Post) (*entities.Post, error) {
+	r.created = post
+	r.writes++
+	return &entities.Post{ID: "p2", Author: post.Author}, nil
+}
+
+func (r *fakePosts) UpdatePost(_ context.Context, id string, updates map[string]interface{}) (*entities.Post, error) {
+	r.updates = updates
+	r.writes++
+	return &entities.Post{ID: id, Author: "u1"}, nil
+}
+
+func (r *fakePosts) DeletePost(context.Context, string) error {
+	r.writes++
+	return nil
+}
+
+func callerContext(id *entities.Identity) context.Context {
+	if id == nil {
+		return context.Background()
+	}
+	return identity.NewContext(context.Background(), id)
+}
+
+func TestPostOwnership(t *testing.T) {
+	owner := &entities.Identity{UserID: "u1", AccountType: policy.RoleUser}
+	other := &entities.Identity{UserID: "u2", AccountType: policy.RoleUser}
+	admin := &entities.Identity{UserID: "a1", AccountType: policy.RoleAdmin}
+
+	tests := []struct {
+		name   string
+		caller *entities.Identity
+		id     string
+		status int
+	}{
+		{"owner", owner, "p1", 0},
+		{"someone else", other, "p1", http.StatusForbidden},
+		{"admin", admin, "p1", 0},
+		{"anonymous", nil, "p1", http.StatusUnauthorized},
+		{"missing post", other, "missing", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writes := 0
+			if tt.status == 0 {
+				writes = 1
+			}
+
+			repo := &fakePosts{}
+			service := NewPostsService(repo, Options{})
+			_, apiErr := service.UpdatePost(callerContext(tt.caller), tt.id, map[string]interface{}{"title": "t", "author": "u2"})
+			if status := statusOf(apiErr); status != tt.status || repo.writes != writes {
+				t.Fatalf("UpdatePost() status = %d with %d writes, want %d with %d", status, repo.writes, tt.status, writes)
+			}
+			if _, ok := repo.updates["author"]; ok {
+				t.Fatal("UpdatePost() let the author be changed")
+			}
+
+			repo = &fakePosts{}
+			service = NewPostsService(repo, Options{})
+			apiErr = service.DeletePost(callerContext(tt.caller), tt.id)
+			if status := statusOf(apiErr); status != tt.status || repo.writes != writes {
+				t.Fatalf("DeletePost() status = %d with %d writes, want %d with %d", status, repo.writes, tt.status, writes)
+			}
+		})
+	}
+}
+
+func TestCreatePostTakesTheAuthorFromTheToken(t *testing.T) {
+	repo := &fakePosts{}
+	service := NewPostsService(repo, Options{})
+
+	if _, apiErr := service.CreatePost(context.Background(), dtos.Post{Title: "t", Author: "u2"}); statusOf(apiErr) != http.StatusUnauthorized {
+		t.Fatalf("CreatePost() without identity = %v, want a 401", apiErr)
+	}
+	caller := &entities.Identity{UserID: "u1", AccountType: policy.RoleUser}
+	if _, apiErr := service.CreatePost(callerContext(caller), dtos.Post{Title: "t", Author: "u2"}); apiErr != nil {
+		t.Fatalf("CreatePost() error = %v", apiErr)
+	}
+	if repo.created.Author != "u1" {
+		t.Fatalf("created post author = %q, want the caller u1", repo.created.Author)
+	}
+}
+
+func statusOf(apiErr utils.ApiError) int {
+	if apiErr == nil {
+		return 0
+	}
+	return apiErr.Status()
+}
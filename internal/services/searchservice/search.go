@@ -0,0 +1,237 @@
+package searchservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/domain/entities"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/search"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/services/catalogservice"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/services/postservice"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/utils"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Peso de cada campo al rankear: un acierto en el nombre vale más que en la descripción.
+const (
+	titleWeight       = 3
+	categoryWeight    = 2
+	ingredientWeight  = 2
+	descriptionWeight = 1
+)
+
+// notReadyRetry is the Retry-After sent until the first refresh succeeds,
+// and how often that refresh is retried.
+const notReadyRetry = 5 * time.Second
+
+var Kinds = []string{search.KindLiquor, search.KindRecipe, search.KindPost}
+
+type (
+	ISearch interface {
+		Search(ctx context.Context, query string, kinds []string, limit int) ([]search.Result, utils.ApiError)
+		// Warm builds the index, rebuilds it every refresh interval and
+		// reloads the kinds reported to Changes, until ctx ends.
+		Warm(ctx context.Context)
+	}
+
+	Options struct {
+		RefreshInterval time.Duration
+		// Changes reports writes made through the gateway, which are indexed
+		// right away instead of at the next refresh.
+		Changes *search.Changes
+	}
+
+	searchService struct {
+		catalogService  catalogservice.ICatalog
+		postsService    postservice.PostsService
+		refreshInterval time.Duration
+		changes         *search.Changes
+		index           *search.Index
+		ready           atomic.Bool
+
+		mu sync.Mutex
+		// docs keeps the last documents loaded of each kind, so an upstream
+		// that fails does not empty its part of the index.
+		docs map[string][]search.Document
+	}
+)
+
+func NewSearchService(catalogService catalogservice.ICatalog, postsService postservice.PostsService, opts Options) ISearch {
+	return &searchService{
+		catalogService:  catalogService,
+		postsService:    postsService,
+		refreshInterval: opts.RefreshInterval,
+		changes:         opts.Changes,
+		index:           search.NewIndex(),
+		docs:            make(map[string][]search.Document),
+	}
+}
+
+func (s *searchService) Search(ctx context.Context, query string, kinds []string, limit int) ([]search.Result, utils.ApiError) {
+	if len(search.Tokens(query)) == 0 {
+		return nil, utils.NewApiError(errors.New("q is required"), http.StatusBadRequest)
+	}
+	for _, kind := range kinds {
+		if !slices.Contains(Kinds, kind) {
+			return nil, utils.NewApiError(fmt.Errorf("type must be one of %s, got %q", strings.Join(Kinds, ", "), kind), http.StatusBadRequest)
+		}
+	}
+	switch {
+	case limit == 0:
+		limit = DefaultLimit
+	case limit < 0 || limit > MaxLimit:
+		return nil, utils.NewApiError(fmt.Errorf("limit must be between 1 and %d", MaxLimit), http.StatusBadRequest)
+	}
+	if !s.ready.Load() {
+		return nil, utils.NewUnavailableError(errors.New("search index is not ready yet"), notReadyRetry)
+	}
+	return s.index.Search(query, kinds, limit), nil
+}
+
+func (s *searchService) Warm(ctx context.Context) {
+	go func() {
+		// Hasta el primer índice se reintenta seguido; después, cada refreshInterval.
+		ticker := time.NewTicker(min(s.refreshInterval, notReadyRetry))
+		defer ticker.Stop()
+		warming := true
+		kinds := Kinds
+		for {
+			s.refresh(ctx, kinds)
+			if warming && s.ready.Load() {
+				warming = false
+				ticker.Reset(s.refreshInterval)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				kinds = Kinds
+				// Un refresco completo ya incluye lo que se escribió.
+				s.changes.Take()
+			case <-s.changes.Wait():
+				kinds = s.changes.Take()
+			}
+		}
+	}()
+}
+
+// refresh reloads the given kinds of documents and swaps the index; the
+// other kinds keep their last documents.
+func (s *searchService) refresh(ctx context.Context, kinds []string) {
+	loaders := map[string]func(context.Context) ([]search.Document, utils.ApiError){
+		search.KindLiquor: s.liquorDocuments,
+		search.KindRecipe: s.recipeDocuments,
+		search.KindPost:   s.postDocuments,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	loaded := 0
+	for _, kind := range kinds {
+		docs, apiErr := loaders[kind](ctx)
+		if apiErr != nil {
+			slog.ErrorContext(ctx, "search index refresh failed", "type", kind, "error", apiErr.Message())
+			continue
+		}
+		s.docs[kind] = docs
+		loaded++
+	}
+	if loaded == 0 {
+		return
+	}
+
+	var all []search.Document
+	for _, kind := range Kinds {
+		all = append(all, s.docs[kind]...)
+	}
+	s.index.Replace(all)
+	s.ready.Store(true)
+	slog.InfoContext(ctx, "search index refreshed", "types", kinds, "documents", len(all))
+}
+
+func (s *searchService) liquorDocuments(ctx context.Context) ([]search.Document, utils.ApiError) {
+	liquors, apiErr := s.catalogService.GetLiquors(ctx)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	docs := make([]search.Document, 0, len(liquors))
+	for _, liquor := range liquors {
+		docs = append(docs, search.Document{
+			Kind:  search.KindLiquor,
+			ID:    liquor.ID,
+			Title: liquor.Name,
+			Fields: []search.Field{
+				{Text: liquor.Name, Weight: titleWeight},
+				{Text: liquor.Category, Weight: categoryWeight},
+				{Text: liquor.Description, Weight: descriptionWeight},
+			},
+			Item: liquor,
+		})
+	}
+	return docs, nil
+}
+
+func (s *searchService) recipeDocuments(ctx context.Context) ([]search.Document, utils.ApiError) {
+	recipes, apiErr := s.catalogService.GetRecipes(ctx)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	docs := make([]search.Document, 0, len(recipes))
+	for _, recipe := range recipes {
+		docs = append(docs, search.Document{
+			Kind:  search.KindRecipe,
+			ID:    recipe.ID,
+			Title: recipe.Name,
+			Fields: []search.Field{
+				{Text: recipe.Name, Weight: titleWeight},
+				{Text: recipe.Category, Weight: categoryWeight},
+				{Text: ingredientNames(recipe), Weight: ingredientWeight},
+				{Text: recipe.Description, Weight: descriptionWeight},
+			},
+			Item: recipe,
+		})
+	}
+	return docs, nil
+}
+
+func (s *searchService) postDocuments(ctx context.Context) ([]search.Document, utils.ApiError) {
+	posts, apiErr := s.postsService.GetPosts(ctx)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	docs := make([]search.Document, 0, len(posts))
+	for _, post := range posts {
+		docs = append(docs, search.Document{
+			Kind:  search.KindPost,
+			ID:    post.ID,
+			Title: post.Title,
+			Fields: []search.Field{
+				{Text: post.Title, Weight: titleWeight},
+				{Text: post.Content, Weight: descriptionWeight},
+			},
+			Item: post,
+		})
+	}
+	return docs, nil
+}
+
+func ingredientNames(recipe entities.Recipe) string {
+	names := make([]string, 0, len(recipe.Ingredients)+len(recipe.Liquors))
+	for _, ingredient := range recipe.Ingredients {
+		names = append(names, ingredient.Name)
+	}
+	return strings.Join(append(names, recipe.Liquors...), " ")
+}
@@ -0,0 +1,79 @@
+package tokens
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Por encima de este tamaño se purgan las entradas vencidas al insertar.
+const cacheSweepSize = 1024
+
+type (
+	// Cache keeps recently verified tokens keyed by their SHA-256, so the raw
+	// token never sits in memory as a map key.
+	Cache struct {
+		ttl time.Duration
+		now func() time.Time
+
+		mu      sync.Mutex
+		entries map[string]cacheEntry
+	}
+
+	cacheEntry struct {
+		claims     *Claims
+		validUntil time.Time
+	}
+)
+
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{ttl: ttl, now: time.Now, entries: make(map[string]cacheEntry)}
+}
+
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Cache) Get(token string) (*Claims, bool) {
+	if c == nil || c.ttl <= 0 {
+		return nil, false
+	}
+	key := Hash(token)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if c.now().After(entry.validUntil) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.claims, true
+}
+
+// Put caches claims for the cache TTL, or until the token expires if that is sooner.
+func (c *Cache) Put(token string, claims *Claims) {
+	if c == nil || c.ttl <= 0 {
+		return
+	}
+	now := c.now()
+	validUntil := now.Add(c.ttl)
+	if !claims.ExpiresAt.IsZero() && claims.ExpiresAt.Before(validUntil) {
+		validUntil = claims.ExpiresAt
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= cacheSweepSize {
+		for key, entry := range c.entries {
+			if now.After(entry.validUntil) {
+				delete(c.entries, key)
+			}
+		}
+	}
+	c.entries[Hash(token)] = cacheEntry{claims: claims, validUntil: validUntil}
+}
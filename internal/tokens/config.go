@@ -0,0 +1,42 @@
+package tokens
+
+import (
+	"fmt"
+	"time"
+)
+
+type Config struct {
+	// Algorithm is HS256, RS256 or empty to always verify against the auth service.
+	Algorithm   string
+	Secret      string
+	JWKSFile    string
+	JWKSPath    string
+	JWKSRefresh time.Duration
+	Leeway      time.Duration
+	// RemoteFallback lets tokens that cannot be checked locally go to /v1/verify.
+	RemoteFallback bool
+	CacheTTL       time.Duration
+	// SessionMaxAge is how long a login is remembered past its expiration.
+	SessionMaxAge time.Duration
+}
+
+// NewVerifier builds the local verifier described by cfg, or nil when local
+// validation is disabled. remote loads the JWKS from the auth service.
+func NewVerifier(cfg Config, remote Loader) (*Verifier, error) {
+	switch cfg.Algorithm {
+	case "":
+		return nil, nil
+	case HS256:
+		if cfg.Secret == "" {
+			return nil, fmt.Errorf("AUTH_JWT_SECRET is required for %s", HS256)
+		}
+		return NewHS256Verifier([]byte(cfg.Secret), cfg.Leeway), nil
+	case RS256:
+		load := remote
+		if cfg.JWKSFile != "" {
+			load = FileLoader(cfg.JWKSFile)
+		}
+		return NewRS256Verifier(NewJWKS(load, cfg.JWKSRefresh), cfg.Leeway), nil
+	}
+	return nil, fmt.Errorf("unsupported AUTH_JWT_ALG %q", cfg.Algorithm)
+}
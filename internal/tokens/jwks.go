@@ -0,0 +1,191 @@
+package tokens
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// Con un kid desconocido se vuelve a pedir el JWKS, pero no más seguido que esto.
+	minRefetchInterval = 30 * time.Second
+	// reloadTimeout bounds a reload, which does not end with the request
+	// that started it.
+	reloadTimeout = 10 * time.Second
+)
+
+type (
+	Loader func(ctx context.Context) ([]byte, error)
+
+	// JWKS caches the RSA keys of a JSON Web Key Set and reloads them every
+	// refresh. Only one reload runs at a time and it happens outside mu, so
+	// verifications with known keys never wait for the network.
+	JWKS struct {
+		load    Loader
+		refresh time.Duration
+		now     func() time.Time
+
+		mu          sync.Mutex
+		keys        map[string]*rsa.PublicKey
+		loadedAt    time.Time
+		lastAttempt time.Time
+		// reloading is closed when the running reload ends; nil if none runs.
+		reloading chan struct{}
+	}
+
+	jwk struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Alg string `json:"alg"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	}
+)
+
+func NewJWKS(load Loader, refresh time.Duration) *JWKS {
+	return &JWKS{load: load, refresh: refresh, now: time.Now}
+}
+
+func FileLoader(path string) Loader {
+	return func(ctx context.Context) ([]byte, error) {
+		return os.ReadFile(path)
+	}
+}
+
+// Key returns the key for kid. A stale set is still used while it reloads
+// in the background; a missing set or an unknown kid waits for a reload, and
+// reloads start at most once every minRefetchInterval.
+func (j *JWKS) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	j.mu.Lock()
+	now := j.now()
+	stale := j.refresh > 0 && now.Sub(j.loadedAt) > j.refresh
+	key, known := j.lookup(kid)
+	canRetry := now.Sub(j.lastAttempt) >= minRefetchInterval
+	loaded := j.keys != nil
+	var reloading chan struct{}
+	switch {
+	case known:
+		if stale && canRetry {
+			j.startReload(ctx)
+		}
+		j.mu.Unlock()
+		return key, nil
+	case canRetry || j.reloading != nil:
+		reloading = j.startReload(ctx)
+	}
+	j.mu.Unlock()
+
+	if reloading == nil {
+		if !loaded {
+			return nil, ErrKeysUnavailable
+		}
+		return nil, ErrUnknownKey
+	}
+	select {
+	case <-reloading:
+	case <-ctx.Done():
+		return nil, ErrKeysUnavailable
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.keys == nil {
+		return nil, ErrKeysUnavailable
+	}
+	if key, known = j.lookup(kid); !known {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// startReload starts a reload unless one is running and returns the channel
+// closed when it ends. j.mu must be held.
+func (j *JWKS) startReload(ctx context.Context) chan struct{} {
+	if j.reloading == nil {
+		j.reloading = make(chan struct{})
+		j.lastAttempt = j.now()
+		// Un cliente que cancela no corta la recarga que esperan los demás.
+		go j.reload(context.WithoutCancel(ctx), j.reloading)
+	}
+	return j.reloading
+}
+
+func (j *JWKS) reload(ctx context.Context, done chan struct{}) {
+	ctx, cancel := context.WithTimeout(ctx, reloadTimeout)
+	defer cancel()
+
+	keys, err := j.fetch(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "jwks reload failed", "error", err)
+	}
+
+	j.mu.Lock()
+	if err == nil {
+		j.keys = keys
+		j.loadedAt = j.now()
+	}
+	j.reloading = nil
+	j.mu.Unlock()
+	close(done)
+}
+
+func (j *JWKS) fetch(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	raw, err := j.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(raw)
+}
+
+func (j *JWKS) lookup(kid string) (*rsa.PublicKey, bool) {
+	if key, ok := j.keys[kid]; ok {
+		return key, true
+	}
+	// Un token sin kid es válido solo si el set tiene una única llave.
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+func ParseJWKS(raw []byte) (map[string]*rsa.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != RS256) {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("jwks has no usable RSA signing keys")
+	}
+	return keys, nil
+}
@@ -0,0 +1,215 @@
+package tokens
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+
+	// AccountTypeClaim carries the account type. It is not "type", which
+	// Login and Register already use for something else.
+	AccountTypeClaim = "account_type"
+)
+
+var (
+	ErrMalformed       = errors.New("malformed token")
+	ErrUnsupportedAlg  = errors.New("unsupported token algorithm")
+	ErrSignature       = errors.New("invalid token signature")
+	ErrExpired         = errors.New("token expired")
+	ErrNotYetValid     = errors.New("token not valid yet")
+	ErrUnknownKey      = errors.New("unknown signing key")
+	ErrKeysUnavailable = errors.New("signing keys unavailable")
+)
+
+type (
+	// Claims is the part of the token the gateway cares about.
+	Claims struct {
+		Subject     string
+		AccountType string
+		ExpiresAt   time.Time
+		NotBefore   time.Time
+		Raw         map[string]interface{}
+	}
+
+	KeySet interface {
+		Key(ctx context.Context, kid string) (*rsa.PublicKey, error)
+	}
+
+	// Verifier validates tokens locally, without calling the auth service.
+	Verifier struct {
+		algorithm string
+		secret    []byte
+		keys      KeySet
+		leeway    time.Duration
+		now       func() time.Time
+	}
+
+	header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+		Typ string `json:"typ"`
+	}
+)
+
+func NewHS256Verifier(secret []byte, leeway time.Duration) *Verifier {
+	return &Verifier{algorithm: HS256, secret: secret, leeway: leeway, now: time.Now}
+}
+
+func NewRS256Verifier(keys KeySet, leeway time.Duration) *Verifier {
+	return &Verifier{algorithm: RS256, keys: keys, leeway: leeway, now: time.Now}
+}
+
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, ErrMalformed
+	}
+	// Nunca se acepta el algoritmo que elija el token, solo el configurado.
+	if h.Alg != v.algorithm {
+		return nil, ErrUnsupportedAlg
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	if err := v.checkSignature(ctx, h.Kid, signed, signature); err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return nil, ErrMalformed
+	}
+	claims := claimsFrom(raw)
+
+	now := v.now()
+	if !claims.ExpiresAt.IsZero() && now.After(claims.ExpiresAt.Add(v.leeway)) {
+		return nil, ErrExpired
+	}
+	if !claims.NotBefore.IsZero() && now.Add(v.leeway).Before(claims.NotBefore) {
+		return nil, ErrNotYetValid
+	}
+	return claims, nil
+}
+
+// ParseUnverified reads the claims of a token whose signature has already
+// been checked elsewhere, such as by the auth service's /v1/verify.
+func ParseUnverified(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+	var raw map[string]interface{}
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return nil, ErrMalformed
+	}
+	return claimsFrom(raw), nil
+}
+
+func (v *Verifier) checkSignature(ctx context.Context, kid string, signed, signature []byte) error {
+	switch v.algorithm {
+	case HS256:
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrSignature
+		}
+	case RS256:
+		key, err := v.keys.Key(ctx, kid)
+		if err != nil {
+			return err
+		}
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return ErrSignature
+		}
+	default:
+		return ErrUnsupportedAlg
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func claimsFrom(raw map[string]interface{}) *Claims {
+	claims := &Claims{
+		Subject:     firstString(raw, "id", "user_id", "sub"),
+		AccountType: firstString(raw, AccountTypeClaim),
+		Raw:         raw,
+	}
+	if exp, ok := numericDate(raw["exp"]); ok {
+		claims.ExpiresAt = exp
+	}
+	if nbf, ok := numericDate(raw["nbf"]); ok {
+		claims.NotBefore = nbf
+	}
+	return claims
+}
+
+func firstString(raw map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		switch v := raw[key].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case float64:
+			return fmt.Sprintf("%.0f", v)
+		}
+	}
+	return ""
+}
+
+func numericDate(v interface{}) (time.Time, bool) {
+	switch n := v.(type) {
+	case float64:
+		return time.Unix(int64(n), 0), true
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return time.Unix(i, 0), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ParseExpiration reads SuccessfulLogin.Expiration as RFC 3339 or unix seconds.
+func ParseExpiration(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339Nano, time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+		return time.Unix(int64(secs), 0), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,229 @@
+package tokens
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func signHS256(t *testing.T, secret []byte, header, claims map[string]interface{}) string {
+	t.Helper()
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, header, claims map[string]interface{}) string {
+	t.Helper()
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func jwksFor(t *testing.T, kid string, key *rsa.PublicKey) []byte {
+	t.Helper()
+	raw, err := json.Marshal(map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"alg": RS256,
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func claimsAt(exp time.Time) map[string]interface{} {
+	return map[string]interface{}{"sub": "u1", "account_type": "admin", "exp": exp.Unix()}
+}
+
+func TestVerifyHS256(t *testing.T) {
+	secret := []byte("secret")
+	hs := map[string]interface{}{"alg": HS256, "typ": "JWT"}
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"valid", signHS256(t, secret, hs, claimsAt(testNow.Add(time.Hour))), nil},
+		{"expired", signHS256(t, secret, hs, claimsAt(testNow.Add(-time.Hour))), ErrExpired},
+		{"expired within leeway", signHS256(t, secret, hs, claimsAt(testNow.Add(-10*time.Second))), nil},
+		{"not valid yet", signHS256(t, secret, hs, map[string]interface{}{"sub": "u1", "nbf": testNow.Add(time.Hour).Unix()}), ErrNotYetValid},
+		{"wrong secret", signHS256(t, []byte("other"), hs, claimsAt(testNow.Add(time.Hour))), ErrSignature},
+		{"alg none", encodeSegment(t, map[string]string{"alg": "none"}) + "." + encodeSegment(t, claimsAt(testNow.Add(time.Hour))) + ".", ErrUnsupportedAlg},
+		{"alg RS256", signHS256(t, secret, map[string]interface{}{"alg": RS256}, claimsAt(testNow.Add(time.Hour))), ErrUnsupportedAlg},
+		{"two segments", "a.b", ErrMalformed},
+		{"bad header", "%%%.e30.sig", ErrMalformed},
+	}
+
+	verifier := NewHS256Verifier(secret, 30*time.Second)
+	verifier.now = func() time.Time { return testNow }
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(context.Background(), tt.token)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.want)
+			}
+			if err == nil && (claims.Subject != "u1" || (claims.AccountType != "" && claims.AccountType != "admin")) {
+				t.Fatalf("Verify() claims = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestVerifyRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := jwksFor(t, "k1", &key.PublicKey)
+	publicDER := x509.MarshalPKCS1PublicKey(&key.PublicKey)
+	valid := claimsAt(testNow.Add(time.Hour))
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"valid", signRS256(t, key, map[string]interface{}{"alg": RS256, "kid": "k1"}, valid), nil},
+		{"single key without kid", signRS256(t, key, map[string]interface{}{"alg": RS256}, valid), nil},
+		{"expired", signRS256(t, key, map[string]interface{}{"alg": RS256, "kid": "k1"}, claimsAt(testNow.Add(-time.Hour))), ErrExpired},
+		{"unknown kid", signRS256(t, key, map[string]interface{}{"alg": RS256, "kid": "k2"}, valid), ErrUnknownKey},
+		{"signed by another key", signRS256(t, other, map[string]interface{}{"alg": RS256, "kid": "k1"}, valid), ErrSignature},
+		// Firmar con HS256 usando la llave pública como secreto es el ataque clásico.
+		{"HS256 with the public key", signHS256(t, publicDER, map[string]interface{}{"alg": HS256, "kid": "k1"}, valid), ErrUnsupportedAlg},
+	}
+
+	jwks := NewJWKS(func(context.Context) ([]byte, error) { return set, nil }, time.Hour)
+	jwks.now = func() time.Time { return testNow }
+	verifier := NewRS256Verifier(jwks, 0)
+	verifier.now = func() time.Time { return testNow }
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := verifier.Verify(context.Background(), tt.token); !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestJWKSUnknownKidRefetch(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := jwksFor(t, "k1", &key.PublicKey)
+	var loads atomic.Int32
+	now := testNow
+	jwks := NewJWKS(func(context.Context) ([]byte, error) {
+		loads.Add(1)
+		return set, nil
+	}, time.Hour)
+	jwks.now = func() time.Time { return now }
+
+	steps := []struct {
+		name      string
+		advance   time.Duration
+		kid       string
+		want      error
+		wantLoads int32
+	}{
+		{"first use loads the set", 0, "k1", nil, 1},
+		{"known kid uses the cache", time.Second, "k1", nil, 1},
+		{"unknown kid right after a load is not reloaded", time.Second, "k9", ErrUnknownKey, 1},
+		{"unknown kid after the interval reloads", minRefetchInterval, "k9", ErrUnknownKey, 2},
+		{"unknown kid again is not reloaded", time.Second, "k9", ErrUnknownKey, 2},
+		{"known kid does not wait for the interval", time.Second, "k1", nil, 2},
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		if _, err := jwks.Key(context.Background(), step.kid); !errors.Is(err, step.want) {
+			t.Fatalf("%s: Key() error = %v, want %v", step.name, err, step.want)
+		}
+		if got := loads.Load(); got != step.wantLoads {
+			t.Fatalf("%s: loads = %d, want %d", step.name, got, step.wantLoads)
+		}
+	}
+}
+
+func TestJWKSCancelledCallerDoesNotFailReload(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := jwksFor(t, "k1", &key.PublicKey)
+	release := make(chan struct{})
+	jwks := NewJWKS(func(ctx context.Context) ([]byte, error) {
+		<-release
+		return set, ctx.Err()
+	}, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := jwks.Key(ctx, "k1"); !errors.Is(err, ErrKeysUnavailable) {
+		t.Fatalf("Key() with a cancelled context error = %v, want %v", err, ErrKeysUnavailable)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := jwks.Key(context.Background(), "k1")
+		done <- err
+	}()
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Key() waiting on the same reload error = %v", err)
+	}
+}
+
+func TestClaimsAccountType(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  map[string]interface{}
+		want string
+	}{
+		{"account_type claim", map[string]interface{}{"account_type": "admin"}, "admin"},
+		{"login type is not the account type", map[string]interface{}{"type": "google"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := claimsFrom(tt.raw).AccountType; got != tt.want {
+				t.Fatalf("AccountType = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
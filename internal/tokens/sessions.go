@@ -0,0 +1,67 @@
+package tokens
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/cache"
+)
+
+const sessionPrefix = "session:"
+
+type (
+	// Sessions remembers the identity and expiration returned by Login so
+	// tokens issued through the gateway are held to
+	// SuccessfulLogin.Expiration. They live in a cache.Store: with a store
+	// shared by every replica, a login seen by one is enforced by all, and a
+	// session that is lost only leaves the token's own exp claim.
+	Sessions struct {
+		store  cache.Store
+		maxAge time.Duration
+		now    func() time.Time
+	}
+
+	session struct {
+		Subject     string    `json:"sub"`
+		AccountType string    `json:"account_type"`
+		ExpiresAt   time.Time `json:"expires_at"`
+	}
+)
+
+func NewSessions(store cache.Store, maxAge time.Duration) *Sessions {
+	return &Sessions{store: store, maxAge: maxAge, now: time.Now}
+}
+
+func (s *Sessions) Remember(ctx context.Context, token string, claims *Claims) {
+	keepUntil := s.now().Add(s.maxAge)
+	if !claims.ExpiresAt.IsZero() {
+		keepUntil = claims.ExpiresAt.Add(s.maxAge)
+	}
+	value, err := json.Marshal(session{Subject: claims.Subject, AccountType: claims.AccountType, ExpiresAt: claims.ExpiresAt})
+	if err != nil {
+		return
+	}
+	entry := cache.Entry{Value: value, FreshUntil: keepUntil, StaleUntil: keepUntil}
+	if err := s.store.Set(ctx, sessionPrefix+Hash(token), entry); err != nil {
+		slog.WarnContext(ctx, "session write failed", "error", err)
+	}
+}
+
+// Lookup returns the login claims for token, including ones already expired.
+func (s *Sessions) Lookup(ctx context.Context, token string) (*Claims, bool) {
+	entry, ok, err := s.store.Get(ctx, sessionPrefix+Hash(token))
+	if err != nil {
+		slog.WarnContext(ctx, "session read failed", "error", err)
+		return nil, false
+	}
+	if !ok || !s.now().Before(entry.StaleUntil) {
+		return nil, false
+	}
+	var stored session
+	if err := json.Unmarshal(entry.Value, &stored); err != nil {
+		return nil, false
+	}
+	return &Claims{Subject: stored.Subject, AccountType: stored.AccountType, ExpiresAt: stored.ExpiresAt}, true
+}
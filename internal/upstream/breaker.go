@@ -0,0 +1,208 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/metrics"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+var ErrCircuitOpen = errors.New("circuit open")
+
+type (
+	State int
+
+	BreakerConfig struct {
+		// FailureThreshold is the number of consecutive failures that opens the circuit.
+		FailureThreshold int
+		// OpenTimeout is how long the circuit stays open before letting trial requests through.
+		OpenTimeout time.Duration
+		// HalfOpenRequests is the number of trial requests that must succeed to close the circuit.
+		HalfOpenRequests int
+	}
+
+	BreakerStatus struct {
+		Upstream   string    `json:"upstream"`
+		State      string    `json:"state"`
+		Failures   int       `json:"failures"`
+		OpenedAt   time.Time `json:"opened_at,omitempty"`
+		RetryAfter float64   `json:"retry_after_seconds,omitempty"`
+		LastError  string    `json:"last_error,omitempty"`
+	}
+
+	CircuitOpenError struct {
+		Upstream   string
+		RetryAfter time.Duration
+	}
+
+	Breaker struct {
+		name string
+		cfg  BreakerConfig
+		now  func() time.Time
+
+		mu                sync.Mutex
+		state             State
+		failures          int
+		openedAt          time.Time
+		halfOpenInFlight  int
+		halfOpenSuccesses int
+		lastError         string
+	}
+)
+
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s circuit is open", e.Upstream)
+}
+
+func (e *CircuitOpenError) Unwrap() error {
+	return ErrCircuitOpen
+}
+
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+		HalfOpenRequests: 1,
+	}
+}
+
+func NewBreaker(name string, cfg BreakerConfig) *Breaker {
+	def := DefaultBreakerConfig()
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = def.FailureThreshold
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = def.OpenTimeout
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = def.HalfOpenRequests
+	}
+	metrics.CircuitState.WithLabelValues(name).Set(float64(StateClosed))
+	return &Breaker{name: name, cfg: cfg, now: time.Now}
+}
+
+// Allow reports whether a call may go out, returning a *CircuitOpenError when it may not.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		elapsed := b.now().Sub(b.openedAt)
+		if elapsed < b.cfg.OpenTimeout {
+			return &CircuitOpenError{Upstream: b.name, RetryAfter: b.cfg.OpenTimeout - elapsed}
+		}
+		b.setState(StateHalfOpen)
+		b.halfOpenInFlight = 0
+		b.halfOpenSuccesses = 0
+		fallthrough
+	case StateHalfOpen:
+		if b.halfOpenInFlight >= b.cfg.HalfOpenRequests {
+			return &CircuitOpenError{Upstream: b.name, RetryAfter: time.Second}
+		}
+		b.halfOpenInFlight++
+	}
+	return nil
+}
+
+// Record feeds the outcome of an allowed call back into the breaker.
+func (b *Breaker) Record(ctx context.Context, resp *http.Response, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen && b.halfOpenInFlight > 0 {
+		b.halfOpenInFlight--
+	}
+
+	switch {
+	case err != nil && ctx.Err() == context.Canceled:
+		// El cliente se desconectó; no dice nada sobre la salud del upstream.
+		return
+	case err != nil:
+		b.onFailure(err.Error())
+	case resp.StatusCode >= http.StatusInternalServerError:
+		b.onFailure(resp.Status)
+	default:
+		b.onSuccess()
+	}
+}
+
+func (b *Breaker) onSuccess() {
+	switch b.state {
+	case StateHalfOpen:
+		b.halfOpenSuccesses++
+		if b.halfOpenSuccesses >= b.cfg.HalfOpenRequests {
+			b.setState(StateClosed)
+			b.failures = 0
+		}
+	case StateClosed:
+		b.failures = 0
+	}
+}
+
+func (b *Breaker) onFailure(reason string) {
+	b.lastError = reason
+	switch b.state {
+	case StateHalfOpen:
+		b.trip()
+	case StateClosed:
+		b.failures++
+		if b.failures >= b.cfg.FailureThreshold {
+			b.trip()
+		}
+	}
+}
+
+func (b *Breaker) trip() {
+	metrics.CircuitOpens.WithLabelValues(b.name).Inc()
+	b.setState(StateOpen)
+	b.openedAt = b.now()
+	b.halfOpenInFlight = 0
+	b.halfOpenSuccesses = 0
+}
+
+func (b *Breaker) setState(state State) {
+	b.state = state
+	metrics.CircuitState.WithLabelValues(b.name).Set(float64(state))
+}
+
+func (b *Breaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := BreakerStatus{
+		Upstream:  b.name,
+		State:     b.state.String(),
+		Failures:  b.failures,
+		LastError: b.lastError,
+	}
+	if b.state != StateClosed {
+		status.OpenedAt = b.openedAt
+	}
+	if b.state == StateOpen {
+		if remaining := b.cfg.OpenTimeout - b.now().Sub(b.openedAt); remaining > 0 {
+			status.RetryAfter = remaining.Seconds()
+		}
+	}
+	return status
+}
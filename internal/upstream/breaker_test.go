@@ -0,0 +1,124 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBreakerTransitions(t *testing.T) {
+	cfg := BreakerConfig{FailureThreshold: 3, OpenTimeout: 30 * time.Second, HalfOpenRequests: 1}
+
+	// "ok" and "fail" are an allowed call and its outcome, "wait" moves the
+	// clock past OpenTimeout and "rejected" expects Allow to fail.
+	tests := []struct {
+		name  string
+		steps []string
+		want  State
+	}{
+		{"failures below the threshold", []string{"fail", "fail"}, StateClosed},
+		{"threshold opens", []string{"fail", "fail", "fail"}, StateOpen},
+		{"a success resets the count", []string{"fail", "fail", "ok", "fail", "fail"}, StateClosed},
+		{"open rejects until the cooldown", []string{"fail", "fail", "fail", "rejected"}, StateOpen},
+		{"probe succeeds", []string{"fail", "fail", "fail", "wait", "ok"}, StateClosed},
+		{"probe fails", []string{"fail", "fail", "fail", "wait", "fail", "rejected"}, StateOpen},
+		{"reopened circuit waits again", []string{"fail", "fail", "fail", "wait", "fail", "wait", "ok"}, StateClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Unix(0, 0)
+			b := NewBreaker("test", cfg)
+			b.now = func() time.Time { return now }
+
+			for i, step := range tt.steps {
+				switch step {
+				case "wait":
+					now = now.Add(cfg.OpenTimeout)
+					continue
+				case "rejected":
+					var open *CircuitOpenError
+					if err := b.Allow(); !errors.As(err, &open) {
+						t.Fatalf("step %d: Allow() = %v, want a CircuitOpenError", i, err)
+					}
+					continue
+				}
+				if err := b.Allow(); err != nil {
+					t.Fatalf("step %d: Allow() = %v", i, err)
+				}
+				status := http.StatusOK
+				if step == "fail" {
+					status = http.StatusBadGateway
+				}
+				b.Record(context.Background(), &http.Response{StatusCode: status, Status: http.StatusText(status)}, nil)
+			}
+			if b.state != tt.want {
+				t.Fatalf("state = %s, want %s", b.state, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreakerHalfOpenLetsOneProbeThrough(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewBreaker("test", BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Second, HalfOpenRequests: 1})
+	b.now = func() time.Time { return now }
+	b.Allow()
+	b.Record(context.Background(), nil, errors.New("connection refused"))
+
+	now = now.Add(time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe Allow() = %v", err)
+	}
+	if b.state != StateHalfOpen {
+		t.Fatalf("state = %s, want half_open", b.state)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second Allow() during the probe = %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestBreakerIgnoresCancelledCalls(t *testing.T) {
+	b := NewBreaker("test", BreakerConfig{FailureThreshold: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b.Allow()
+	b.Record(ctx, nil, context.Canceled)
+	if b.state != StateClosed {
+		t.Fatalf("state = %s after a cancelled call, want closed", b.state)
+	}
+}
+
+func TestBreakerPerUpstream(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+
+	breaker := BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute}
+	catalog, _ := NewClient(Config{Name: "catalog-test", BaseURL: failing.URL, Breaker: breaker})
+	posts, _ := NewClient(Config{Name: "posts-test", BaseURL: healthy.URL, Breaker: breaker})
+
+	for range 2 {
+		resp, err := catalog.Get(context.Background(), catalog.URL("/recipes"))
+		if err != nil {
+			t.Fatalf("catalog Get() error = %v", err)
+		}
+		resp.Body.Close()
+	}
+	if _, err := catalog.Get(context.Background(), catalog.URL("/recipes")); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("catalog Get() after the threshold = %v, want ErrCircuitOpen", err)
+	}
+	resp, err := posts.Get(context.Background(), posts.URL("/posts"))
+	if err != nil {
+		t.Fatalf("posts Get() error = %v with the catalog circuit open", err)
+	}
+	resp.Body.Close()
+	if state := posts.Breaker().Status().State; state != "closed" {
+		t.Fatalf("posts circuit = %s, want closed", state)
+	}
+}
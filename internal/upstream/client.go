@@ -0,0 +1,279 @@
+package upstream
+
+import (
+	"context"
+	"fmt"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/certs"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/metrics"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/requestid"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Nombres de los microservicios con los que habla el gateway.
+const (
+	Catalog          = "catalog"
+	Auth             = "auth"
+	AI               = "ai"
+	ImageRecognition = "image_recognition"
+	Scrapping        = "scrapping"
+	Posts            = "posts"
+)
+
+const defaultTimeout = 10 * time.Second
+
+type (
+	Config struct {
+		Name    string
+		BaseURL string
+		Timeout time.Duration
+		Breaker BreakerConfig
+		// HealthPath is what Probe requests; defaults to "/".
+		HealthPath string
+		TLS        TLSConfig
+	}
+
+	// TLSConfig is for microservices behind a private CA or that require a
+	// client certificate from the gateway. Empty means the system roots.
+	TLSConfig struct {
+		CAFile     string
+		CertFile   string
+		KeyFile    string
+		ServerName string
+	}
+
+	TransportConfig struct {
+		MaxIdleConns        int
+		MaxIdleConnsPerHost int
+		MaxConnsPerHost     int
+		IdleConnTimeout     time.Duration
+		DialTimeout         time.Duration
+		TLSHandshakeTimeout time.Duration
+	}
+
+	// Client is the only way repositories reach a microservice. Every client
+	// shares one pooled transport and carries its own timeout and circuit breaker.
+	Client struct {
+		name       string
+		baseURL    string
+		healthPath string
+		http       *http.Client
+		breaker    *Breaker
+	}
+)
+
+var sharedTransport http.RoundTripper = NewTransport(DefaultTransportConfig())
+
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 20,
+		MaxConnsPerHost:     0,
+		IdleConnTimeout:     90 * time.Second,
+		DialTimeout:         5 * time.Second,
+		TLSHandshakeTimeout: 5 * time.Second,
+	}
+}
+
+func NewTransport(cfg TransportConfig) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   cfg.DialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+func (t TLSConfig) IsZero() bool {
+	return t == TLSConfig{}
+}
+
+// NewClient fails only when the TLS files of cfg cannot be loaded.
+func NewClient(cfg Config) (*Client, error) {
+	// Con TLS propio el upstream tiene su pool; el resto comparte el transporte.
+	transport := sharedTransport
+	if !cfg.TLS.IsZero() {
+		tlsConfig, err := certs.ClientTLS(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.ServerName)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", cfg.Name, err)
+		}
+		t := NewTransport(DefaultTransportConfig())
+		t.TLSClientConfig = tlsConfig
+		transport = t
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	healthPath := cfg.HealthPath
+	if healthPath == "" {
+		healthPath = "/"
+	}
+	return &Client{
+		name:       cfg.Name,
+		baseURL:    strings.TrimRight(cfg.BaseURL, "/"),
+		healthPath: healthPath,
+		http: &http.Client{
+			Timeout: timeout,
+			// otelhttp abre un span por llamada e inyecta el traceparent.
+			Transport: otelhttp.NewTransport(transport,
+				otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
+					return cfg.Name + " " + req.Method
+				}),
+			),
+		},
+		breaker: NewBreaker(cfg.Name, cfg.Breaker),
+	}, nil
+}
+
+func (c *Client) Name() string {
+	return c.name
+}
+
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+func (c *Client) Breaker() *Breaker {
+	return c.breaker
+}
+
+// URL joins the base URL with a path built from format and args. String
+// args are path-escaped, so an id cannot add path segments or a query;
+// query strings are appended to the result instead.
+func (c *Client) URL(format string, args ...interface{}) string {
+	escaped := make([]interface{}, len(args))
+	for i, arg := range args {
+		if s, ok := arg.(string); ok {
+			arg = url.PathEscape(s)
+		}
+		escaped[i] = arg
+	}
+	path := fmt.Sprintf(format, escaped...)
+	if path == "" {
+		return c.baseURL
+	}
+	if !strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "?") {
+		path = "/" + path
+	}
+	return c.baseURL + path
+}
+
+func (c *Client) NewRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, method, url, body)
+}
+
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if id := requestid.FromContext(ctx); id != "" {
+		req.Header.Set(requestid.Header, id)
+	}
+	if c.baseURL == "" {
+		return nil, &Error{Upstream: c.name, Kind: KindUnavailable, Err: ErrNotConfigured}
+	}
+	if err := c.breaker.Allow(); err != nil {
+		metrics.CircuitRejections.WithLabelValues(c.name).Inc()
+		slog.WarnContext(ctx, "upstream call rejected", c.logAttrs(req, 0, err)...)
+		return nil, c.transportError(ctx, err)
+	}
+
+	inFlight := metrics.UpstreamInFlight.WithLabelValues(c.name)
+	inFlight.Inc()
+	start := time.Now()
+	resp, err := c.http.Do(req)
+	elapsed := time.Since(start)
+	inFlight.Dec()
+	metrics.UpstreamDuration.WithLabelValues(c.name, req.Method).Observe(elapsed.Seconds())
+
+	c.breaker.Record(ctx, resp, err)
+	if err != nil {
+		upErr := c.transportError(ctx, err)
+		if IsKind(upErr, KindTimeout) {
+			metrics.UpstreamTimeouts.WithLabelValues(c.name).Inc()
+		}
+		metrics.UpstreamRequests.WithLabelValues(c.name, req.Method, "error").Inc()
+		slog.ErrorContext(ctx, "upstream call failed", c.logAttrs(req, elapsed, err)...)
+		return nil, upErr
+	}
+
+	metrics.UpstreamRequests.WithLabelValues(c.name, req.Method, metrics.StatusClass(resp.StatusCode)).Inc()
+	if req.Method == http.MethodGet && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		ValidatorsFrom(ctx).Add(resp.Header.Get("ETag"), resp.Header.Get("Last-Modified"))
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		slog.ErrorContext(ctx, "upstream call failed", append(c.logAttrs(req, elapsed, nil), "status", resp.StatusCode)...)
+	} else {
+		slog.DebugContext(ctx, "upstream call", append(c.logAttrs(req, elapsed, nil), "status", resp.StatusCode)...)
+	}
+	return resp, nil
+}
+
+// Probe checks that the upstream answers at its health path. It bypasses the
+// circuit breaker so readiness reflects the service itself; any status below
+// 500 counts as up, since not every service has a dedicated health route.
+func (c *Client) Probe(ctx context.Context) error {
+	if c.baseURL == "" {
+		return ErrNotConfigured
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL(c.healthPath), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return c.transportError(ctx, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return &Error{Upstream: c.name, Kind: kindForStatus(resp.StatusCode), Status: resp.StatusCode}
+	}
+	return nil
+}
+
+// logAttrs leaves the query string out of the logged URL, since some
+// upstream calls carry user data there.
+func (c *Client) logAttrs(req *http.Request, elapsed time.Duration, err error) []any {
+	attrs := []any{"upstream", c.name, "method", req.Method, "path", req.URL.Path}
+	if elapsed > 0 {
+		attrs = append(attrs, "duration", elapsed)
+	}
+	if err != nil {
+		attrs = append(attrs, "error", err.Error())
+	}
+	return attrs
+}
+
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := c.NewRequest(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+func (c *Client) Post(ctx context.Context, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := c.NewRequest(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return c.Do(req)
+}
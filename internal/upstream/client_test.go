@@ -0,0 +1,29 @@
+package upstream
+
+import "testing"
+
+func TestClientURL(t *testing.T) {
+	client, _ := NewClient(Config{Name: "catalog", BaseURL: "https://catalog.internal/"})
+
+	tests := []struct {
+		name   string
+		format string
+		args   []interface{}
+		want   string
+	}{
+		{"no path", "", nil, "https://catalog.internal"},
+		{"missing slash", "liquors", nil, "https://catalog.internal/liquors"},
+		{"plain id", "/liquors/%s", []interface{}{"l1"}, "https://catalog.internal/liquors/l1"},
+		{"id with a slash", "/liquors/%s", []interface{}{"../admin"}, "https://catalog.internal/liquors/..%2Fadmin"},
+		{"id with a query", "/recipes/%s", []interface{}{"r1?limit=1000"}, "https://catalog.internal/recipes/r1%3Flimit=1000"},
+		{"id with a fragment and spaces", "/posts/%s", []interface{}{"p 1#x"}, "https://catalog.internal/posts/p%201%23x"},
+		{"numbers are not escaped", "/page/%d", []interface{}{2}, "https://catalog.internal/page/2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := client.URL(tt.format, tt.args...); got != tt.want {
+				t.Fatalf("URL(%q, %v) = %q, want %q", tt.format, tt.args, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package upstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	KindUnknown Kind = iota
+	KindNotFound
+	KindConflict
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+	KindUnavailable
+	KindTimeout
+	KindBadResponse
+)
+
+// ErrNotConfigured is returned by Probe when the upstream has no base URL.
+var ErrNotConfigured = errors.New("upstream not configured")
+
+// Solo se copia el mensaje del upstream en errores 4xx y hasta este largo.
+const maxMessageLength = 200
+
+type (
+	Kind int
+
+	// Error describes a failed call to a microservice. Message is only set
+	// when the upstream's own message is safe to show to our clients.
+	Error struct {
+		Upstream string
+		Kind     Kind
+		Status   int
+		Message  string
+		Err      error
+	}
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not found"
+	case KindConflict:
+		return "conflict"
+	case KindValidation:
+		return "validation"
+	case KindUnauthorized:
+		return "unauthorized"
+	case KindForbidden:
+		return "forbidden"
+	case KindUnavailable:
+		return "unavailable"
+	case KindTimeout:
+		return "timeout"
+	case KindBadResponse:
+		return "bad response"
+	default:
+		return "unknown"
+	}
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s: %s", e.Upstream, e.Kind)
+	if e.Status != 0 {
+		msg = fmt.Sprintf("%s (status %d)", msg, e.Status)
+	}
+	if e.Message != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Message)
+	}
+	if e.Err != nil {
+		msg = fmt.Sprintf("%s: %v", msg, e.Err)
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// IsKind reports whether err is an upstream error of the given kind.
+func IsKind(err error, kind Kind) bool {
+	var upErr *Error
+	return errors.As(err, &upErr) && upErr.Kind == kind
+}
+
+// NotFound is returned by repositories when the upstream answers 200 with an empty entity.
+func (c *Client) NotFound(message string) error {
+	return &Error{Upstream: c.name, Kind: KindNotFound, Status: http.StatusNotFound, Message: message}
+}
+
+// Check turns a non-2xx response into an *Error.
+func (c *Client) Check(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	upErr := &Error{Upstream: c.name, Kind: kindForStatus(resp.StatusCode), Status: resp.StatusCode}
+	if resp.StatusCode < http.StatusInternalServerError {
+		upErr.Message = readMessage(resp.Body)
+	}
+	return upErr
+}
+
+// Decode checks the response status and decodes its JSON body into v.
+func (c *Client) Decode(resp *http.Response, v interface{}) error {
+	if err := c.Check(resp); err != nil {
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return &Error{Upstream: c.name, Kind: KindBadResponse, Status: resp.StatusCode, Err: err}
+	}
+	return nil
+}
+
+func (c *Client) transportError(ctx context.Context, err error) error {
+	if ctx.Err() == context.Canceled {
+		return err
+	}
+	var open *CircuitOpenError
+	if errors.As(err, &open) {
+		return &Error{Upstream: c.name, Kind: KindUnavailable, Err: err}
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &Error{Upstream: c.name, Kind: KindTimeout, Err: err}
+	}
+	return &Error{Upstream: c.name, Kind: KindUnavailable, Err: err}
+}
+
+func kindForStatus(status int) Kind {
+	switch status {
+	case http.StatusNotFound:
+		return KindNotFound
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return KindConflict
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return KindValidation
+	case http.StatusUnauthorized:
+		return KindUnauthorized
+	case http.StatusForbidden:
+		return KindForbidden
+	case http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusTooManyRequests:
+		return KindUnavailable
+	case http.StatusGatewayTimeout, http.StatusRequestTimeout:
+		return KindTimeout
+	}
+	if status >= http.StatusInternalServerError {
+		return KindBadResponse
+	}
+	return KindUnknown
+}
+
+// readMessage extracts {"message": ...} or {"error": ...} from a JSON error body.
+func readMessage(body io.Reader) string {
+	raw, err := io.ReadAll(io.LimitReader(body, 4096))
+	if err != nil {
+		return ""
+	}
+	var payload struct {
+		Message string          `json:"message"`
+		Error   json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return ""
+	}
+	message := payload.Message
+	if message == "" && len(payload.Error) > 0 {
+		var asString string
+		var asObject struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(payload.Error, &asString) == nil {
+			message = asString
+		} else if json.Unmarshal(payload.Error, &asObject) == nil {
+			message = asObject.Message
+		}
+	}
+	message = strings.TrimSpace(message)
+	if runes := []rune(message); len(runes) > maxMessageLength {
+		message = string(runes[:maxMessageLength])
+	}
+	return message
+}
@@ -0,0 +1,56 @@
+package upstream
+
+import (
+	"context"
+	"sync"
+)
+
+// Validators collects the ETag and Last-Modified that upstreams send on the
+// reads made for one gateway response. They only describe the response when
+// it comes from a single read, so Get returns nothing once a second read is
+// recorded. A nil *Validators ignores every call.
+type Validators struct {
+	mu           sync.Mutex
+	reads        int
+	etag         string
+	lastModified string
+}
+
+type validatorsKey struct{}
+
+// WithValidators returns a context whose successful GETs are recorded in
+// the returned Validators.
+func WithValidators(ctx context.Context) (context.Context, *Validators) {
+	v := &Validators{}
+	return context.WithValue(ctx, validatorsKey{}, v), v
+}
+
+// ValidatorsFrom returns the Validators of ctx, or nil.
+func ValidatorsFrom(ctx context.Context) *Validators {
+	v, _ := ctx.Value(validatorsKey{}).(*Validators)
+	return v
+}
+
+// Add records one read and the validators it came with, which may be empty.
+func (v *Validators) Add(etag, lastModified string) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reads++
+	v.etag, v.lastModified = etag, lastModified
+}
+
+// Get returns the validators of the only read recorded.
+func (v *Validators) Get() (etag, lastModified string) {
+	if v == nil {
+		return "", ""
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.reads != 1 {
+		return "", ""
+	}
+	return v.etag, v.lastModified
+}
@@ -1,5 +1,13 @@
 package utils
 
+import (
+	"errors"
+	"fmt"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/upstream"
+	"net/http"
+	"time"
+)
+
 type ApiError interface {
 	Message() error
 	Status() int
@@ -28,3 +36,75 @@ func newApiError(message error, status int) *apiErr {
 func NewApiError(message error, status int) ApiError {
 	return newApiError(message, status)
 }
+
+type retryErr struct {
+	*apiErr
+	retryAfter time.Duration
+}
+
+func (e *retryErr) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// NewUnavailableError builds a 503 that tells the client when to try again.
+func NewUnavailableError(message error, retryAfter time.Duration) ApiError {
+	return &retryErr{
+		apiErr:     newApiError(message, http.StatusServiceUnavailable),
+		retryAfter: retryAfter,
+	}
+}
+
+// NewTooManyRequestsError builds a 429 that tells the client when to try again.
+func NewTooManyRequestsError(message error, retryAfter time.Duration) ApiError {
+	return &retryErr{
+		apiErr:     newApiError(message, http.StatusTooManyRequests),
+		retryAfter: retryAfter,
+	}
+}
+
+// RetryAfter returns the retry delay carried by e, if any.
+func RetryAfter(e ApiError) (time.Duration, bool) {
+	if r, ok := e.(interface{ RetryAfter() time.Duration }); ok {
+		return r.RetryAfter(), true
+	}
+	return 0, false
+}
+
+// FromUpstream maps an error returned by a repository to an ApiError,
+// using fallback when the error carries nothing more specific.
+func FromUpstream(err error, fallback ApiError) ApiError {
+	var open *upstream.CircuitOpenError
+	if errors.As(err, &open) {
+		return NewUnavailableError(fmt.Errorf("%s service unavailable", open.Upstream), open.RetryAfter)
+	}
+
+	var upErr *upstream.Error
+	if !errors.As(err, &upErr) {
+		return fallback
+	}
+
+	message := fallback.Message()
+	if upErr.Message != "" {
+		message = errors.New(upErr.Message)
+	}
+
+	switch upErr.Kind {
+	case upstream.KindNotFound:
+		return NewApiError(message, http.StatusNotFound)
+	case upstream.KindConflict:
+		return NewApiError(message, http.StatusConflict)
+	case upstream.KindValidation:
+		return NewApiError(message, http.StatusBadRequest)
+	case upstream.KindUnauthorized:
+		return NewApiError(message, http.StatusUnauthorized)
+	case upstream.KindForbidden:
+		return NewApiError(message, http.StatusForbidden)
+	case upstream.KindUnavailable:
+		return NewApiError(fmt.Errorf("%s service unavailable", upErr.Upstream), http.StatusServiceUnavailable)
+	case upstream.KindTimeout:
+		return NewApiError(fmt.Errorf("%s service timed out", upErr.Upstream), http.StatusGatewayTimeout)
+	case upstream.KindBadResponse:
+		return NewApiError(fmt.Errorf("invalid response from %s service", upErr.Upstream), http.StatusBadGateway)
+	}
+	return fallback
+}
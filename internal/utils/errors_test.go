@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cococtel/Cococtel_Gagateway/internal/upstream"
+)
+
+// secret stands for internals an upstream may put in its error bodies.
+const secret = "pq: relation recipes_internal does not exist"
+
+func TestFromUpstream(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    int
+		message string
+	}{
+		{"not found", http.StatusNotFound, `{"message":"recipe not found"}`, http.StatusNotFound, "recipe not found"},
+		{"conflict", http.StatusConflict, `{"error":"name taken"}`, http.StatusConflict, "name taken"},
+		{"precondition", http.StatusPreconditionFailed, `{}`, http.StatusConflict, "error getting recipe"},
+		{"validation", http.StatusUnprocessableEntity, `{"error":{"message":"name is required"}}`, http.StatusBadRequest, "name is required"},
+		{"unauthorized", http.StatusUnauthorized, `not json`, http.StatusUnauthorized, "error getting recipe"},
+		{"forbidden", http.StatusForbidden, `{"message":"not yours"}`, http.StatusForbidden, "not yours"},
+		{"long message", http.StatusBadRequest, `{"message":"` + strings.Repeat("x", 300) + `"}`, http.StatusBadRequest, strings.Repeat("x", 200)},
+		{"server error", http.StatusInternalServerError, `{"message":"` + secret + `"}`, http.StatusBadGateway, "invalid response from catalog service"},
+		{"bad gateway", http.StatusBadGateway, secret, http.StatusServiceUnavailable, "catalog service unavailable"},
+		{"upstream timeout", http.StatusGatewayTimeout, secret, http.StatusGatewayTimeout, "catalog service timed out"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			client, _ := upstream.NewClient(upstream.Config{Name: "catalog", BaseURL: server.URL})
+
+			assertMapped(t, client, server.URL, tt.want, tt.message)
+		})
+	}
+}
+
+func TestFromUpstreamTransportErrors(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+	timeout, _ := upstream.NewClient(upstream.Config{Name: "catalog", BaseURL: slow.URL, Timeout: 20 * time.Millisecond})
+	assertMapped(t, timeout, slow.URL, http.StatusGatewayTimeout, "catalog service timed out")
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	refused, _ := upstream.NewClient(upstream.Config{Name: "catalog", BaseURL: closed.URL})
+	assertMapped(t, refused, closed.URL, http.StatusServiceUnavailable, "catalog service unavailable")
+
+	// The first failure opens the circuit, so the second call never goes out.
+	breaker, _ := upstream.NewClient(upstream.Config{Name: "catalog", BaseURL: closed.URL, Breaker: upstream.BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute}})
+	breaker.Get(context.Background(), breaker.URL("/recipes"))
+	apiErr := assertMapped(t, breaker, closed.URL, http.StatusServiceUnavailable, "catalog service unavailable")
+	if retryAfter, ok := RetryAfter(apiErr); !ok || retryAfter <= 0 {
+		t.Fatalf("open circuit Retry-After = %v, %v; want a positive delay", retryAfter, ok)
+	}
+}
+
+// assertMapped fetches a recipe through client and checks the ApiError the
+// controllers would answer with.
+func assertMapped(t *testing.T, client *upstream.Client, url string, status int, message string) ApiError {
+	t.Helper()
+	var recipe struct{}
+	err := func() error {
+		resp, err := client.Get(context.Background(), client.URL("/recipes/%s", "r1"))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		return client.Decode(resp, &recipe)
+	}()
+	if err == nil {
+		t.Fatal("fetching the recipe did not fail")
+	}
+
+	apiErr := FromUpstream(err, NewApiError(errors.New("error getting recipe"), http.StatusInternalServerError))
+	got := apiErr.Message().Error()
+	if apiErr.Status() != status || got != message {
+		t.Fatalf("FromUpstream(%v) = %d %q, want %d %q", err, apiErr.Status(), got, status, message)
+	}
+	if strings.Contains(got, url) || strings.Contains(got, "127.0.0.1") || strings.Contains(got, secret) {
+		t.Fatalf("client message %q leaks upstream details", got)
+	}
+	return apiErr
+}
@@ -1,9 +1,13 @@
 package utils
 
 import (
+	"context"
 	"fmt"
+	"github.com/Cococtel/Cococtel_Gagateway/internal/requestid"
 	"github.com/gin-gonic/gin"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -11,9 +15,10 @@ type response struct {
 	Data interface{} `json:"data"`
 }
 type errorResponse struct {
-	Status  int    `json:"-"`
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Status    int    `json:"-"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
 }
 
 func Response(c *gin.Context, status int, data interface{}) {
@@ -24,9 +29,31 @@ func Success(c *gin.Context, status int, data interface{}) {
 }
 func Error(c *gin.Context, status int, format string, args ...interface{}) {
 	err := errorResponse{
-		Code:    strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_"),
-		Message: fmt.Sprintf(format),
-		Status:  status,
+		Code:      strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_"),
+		Message:   fmt.Sprintf(format, args...),
+		Status:    status,
+		RequestID: requestid.FromContext(c.Request.Context()),
 	}
 	Response(c, status, err)
 }
+
+// ApiErrorResponse writes apiErr using the {"data", "error"} envelope shared by the controllers.
+func ApiErrorResponse(c *gin.Context, apiErr ApiError) {
+	if retryAfter, ok := RetryAfter(apiErr); ok && retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+	Response(c, apiErr.Status(), map[string]interface{}{
+		"data":  nil,
+		"error": ErrorBody(c.Request.Context(), apiErr),
+	})
+}
+
+// ErrorBody is the "error" object of the envelope. The request id lets
+// support find the matching gateway and upstream logs.
+func ErrorBody(ctx context.Context, apiErr ApiError) map[string]interface{} {
+	body := map[string]interface{}{"message": apiErr.Message().Error(), "status": apiErr.Status()}
+	if id := requestid.FromContext(ctx); id != "" {
+		body["request_id"] = id
+	}
+	return body
+}